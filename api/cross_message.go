@@ -0,0 +1,36 @@
+package api
+
+import (
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// CrossMessageKind discriminates the direction a cross-message is travelling
+// through the subnet hierarchy.
+type CrossMessageKind string
+
+const (
+	CrossMessageKindTopDown  CrossMessageKind = "top-down"
+	CrossMessageKindBottomUp CrossMessageKind = "bottom-up"
+)
+
+// CrossMessage is the API-facing view of a message routed between subnets by
+// eudico's hierarchical consensus. It mirrors the Cid/Message shape of
+// Message so callers can treat cross-messages uniformly, while adding the
+// routing metadata (From/To subnet, Nonce) that same-subnet messages don't
+// carry.
+type CrossMessage struct {
+	Cid     cid.Cid
+	Message *types.Message
+	Kind    CrossMessageKind
+
+	// From and To are the hierarchical.SubnetID strings of the source and
+	// destination subnets.
+	From string
+	To   string
+	// Nonce is the cross-message nonce assigned by the source subnet's SCA.
+	// It is independent of Message.Nonce, which only tracks per-actor
+	// sequencing within the source subnet.
+	Nonce uint64
+}
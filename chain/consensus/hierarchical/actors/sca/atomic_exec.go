@@ -0,0 +1,247 @@
+package sca
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/filecoin-project/specs-actors/v7/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v7/actors/runtime"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// ExecStatus describes where an atomic execution proposal is in its
+// two-phase commit lifecycle, either as a whole or (in AtomicExec.Status)
+// for a single participant.
+type ExecStatus uint64
+
+const (
+	// ExecInit is a participant that hasn't prepared yet.
+	ExecInit ExecStatus = iota
+	// ExecPrepared is a participant that has locked the referenced state
+	// and submitted its prepare receipt.
+	ExecPrepared
+	// ExecCommitted names the committed outcome. No code path assigns it:
+	// a proposal whose every Status entry is ExecPrepared counts as
+	// committed and is settled as such.
+	ExecCommitted
+	// ExecAborted is the terminal state once any participant aborted, or
+	// the deadline (plus AtomicExecChallengePeriod) passed without full
+	// preparation.
+	ExecAborted
+)
+
+// AtomicExecParams is the proposal an initiator posts to the SCA of the
+// common ancestor subnet where coordination happens.
+type AtomicExecParams struct {
+	// Participants lists every subnet that must prepare for the execution
+	// to commit.
+	Participants []address.SubnetID
+	// Locks is the ordered list of state locks each participant is being
+	// asked to hold for the duration of the execution.
+	Locks []schema.CrossMsgMeta
+	// Deadline is the epoch by which every participant must have prepared.
+	// Past Deadline+AtomicExecChallengePeriod, any participant (or the
+	// initiator) can abort the execution.
+	Deadline abi.ChainEpoch
+}
+
+// AtomicExec is the registry entry tracked per proposal, keyed by the Cid
+// of the AtomicExec entry itself as first stored by initAtomicExec.
+type AtomicExec struct {
+	Params AtomicExecParams
+	// Nonce disambiguates this proposal from another with byte-identical
+	// Params initiated earlier, so replaying an old proposal can't collide
+	// with (or be mistaken for) a fresh one.
+	Nonce uint64
+	// Initiator is who gets GasLocked back once the execution settles.
+	Initiator address.Address
+	// GasLocked is the deposit taken from the initiator in InitAtomicExec,
+	// refunded in full once the execution commits or aborts.
+	GasLocked abi.TokenAmount
+	// Status is parallel to Params.Participants: Status[i] is the status
+	// of Params.Participants[i].
+	Status []ExecStatus
+}
+
+// AtomicExecIDParam names a registered execution by its registry key, the
+// Cid returned by InitAtomicExec.
+type AtomicExecIDParam struct {
+	Cid string
+}
+
+// participantIndex returns the index of participant in ex.Params.Participants, or -1.
+func (ex *AtomicExec) participantIndex(participant address.SubnetID) int {
+	for i, p := range ex.Params.Participants {
+		if p == participant {
+			return i
+		}
+	}
+	return -1
+}
+
+// allPrepared reports whether every participant has reached ExecPrepared.
+func (ex *AtomicExec) allPrepared() bool {
+	for _, s := range ex.Status {
+		if s != ExecPrepared {
+			return false
+		}
+	}
+	return true
+}
+
+// anyAborted reports whether any participant has reached ExecAborted.
+func (ex *AtomicExec) anyAborted() bool {
+	for _, s := range ex.Status {
+		if s == ExecAborted {
+			return true
+		}
+	}
+	return false
+}
+
+// getAtomicExec loads the registry entry for execCid, if any.
+func (st *SCAState) getAtomicExec(s adt.Store, execCid cid.Cid) (*AtomicExec, bool, error) {
+	execs, err := adt.AsMap(s, st.AtomicExecRegistry, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to load atomic exec registry: %w", err)
+	}
+	var out AtomicExec
+	found, err := execs.Get(abi.CidKey(execCid), &out)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to get atomic exec %s: %w", execCid, err)
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return &out, true, nil
+}
+
+// flushAtomicExec persists ex as the registry entry for execCid.
+func (st *SCAState) flushAtomicExec(rt runtime.Runtime, execCid cid.Cid, ex *AtomicExec) {
+	execs, err := adt.AsMap(adt.AsStore(rt), st.AtomicExecRegistry, builtin.DefaultHamtBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load atomic exec registry")
+	err = execs.Put(abi.CidKey(execCid), ex)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to put atomic exec in registry")
+	st.AtomicExecRegistry, err = execs.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush atomic exec registry")
+}
+
+// initAtomicExec registers a new proposal, deposits the initiator's gas
+// lock, and returns its Cid.
+func (st *SCAState) initAtomicExec(rt runtime.Runtime, initiator address.Address, gasLocked abi.TokenAmount, params AtomicExecParams) cid.Cid {
+	if len(params.Participants) == 0 {
+		rt.Abortf(exitcode.ErrIllegalArgument, "atomic execution needs at least one participant")
+	}
+	if params.Deadline <= rt.CurrEpoch() {
+		rt.Abortf(exitcode.ErrIllegalArgument, "atomic execution deadline has already passed")
+	}
+
+	nonce := st.AtomicExecNonce
+	st.AtomicExecNonce++
+
+	ex := &AtomicExec{
+		Params:    params,
+		Nonce:     nonce,
+		Initiator: initiator,
+		GasLocked: gasLocked,
+		Status:    make([]ExecStatus, len(params.Participants)),
+	}
+
+	// Nonce makes ex content-unique even if an identical proposal was made
+	// before, so its Cid can double as the registry key.
+	execCid, err := adt.AsStore(rt).Put(rt.Context(), ex)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to compute atomic exec Cid")
+
+	st.flushAtomicExec(rt, execCid, ex)
+	return execCid
+}
+
+// recordAtomicExecStatus moves participant's status forward to next for
+// execCid, settling the whole proposal (zeroing out the initiator's gas
+// lock, for the caller to refund via rt.Send once its transaction closes)
+// once every participant has prepared or any one has aborted.
+//
+// Its only caller is SubmitAtomicExec (direct submission by a participant
+// one level below this SCA); applyCheckMsgs does not route prepares or
+// aborts forwarded up from deeper subnets here. It only touches adt.Store,
+// never rt.Send, so it's safe to call from inside an open
+// rt.StateTransaction.
+func (st *SCAState) recordAtomicExecStatus(rt runtime.Runtime, execCid cid.Cid, participant address.SubnetID, next ExecStatus) (refundTo address.Address, refund abi.TokenAmount) {
+	ex, found, err := st.getAtomicExec(adt.AsStore(rt), execCid)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load atomic exec")
+	if !found {
+		rt.Abortf(exitcode.ErrIllegalArgument, "no atomic execution registered for this Cid")
+	}
+
+	idx := ex.participantIndex(participant)
+	if idx < 0 {
+		rt.Abortf(exitcode.ErrIllegalArgument, "subnet is not a participant in this atomic execution")
+	}
+	if ex.Status[idx] != ExecInit {
+		// Already settled for this participant; submitting twice is a
+		// no-op rather than an error, so a retried checkpoint message
+		// doesn't abort an otherwise-healthy execution.
+		return address.Undef, big.Zero()
+	}
+	if ex.anyAborted() {
+		return address.Undef, big.Zero()
+	}
+
+	ex.Status[idx] = next
+
+	if ex.anyAborted() || ex.allPrepared() {
+		refundTo, refund = st.settleAtomicExec(ex)
+	}
+
+	st.flushAtomicExec(rt, execCid, ex)
+	return refundTo, refund
+}
+
+// abortAtomicExec aborts every participant that hasn't already settled,
+// either because a participant requested it or because the deadline (plus
+// its challenge period) has passed. Like recordAtomicExecStatus, it only
+// touches adt.Store; the caller sends the returned refund after its own
+// transaction closes.
+func (st *SCAState) abortAtomicExec(rt runtime.Runtime, execCid cid.Cid) (refundTo address.Address, refund abi.TokenAmount) {
+	ex, found, err := st.getAtomicExec(adt.AsStore(rt), execCid)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load atomic exec")
+	if !found {
+		rt.Abortf(exitcode.ErrIllegalArgument, "no atomic execution registered for this Cid")
+	}
+	if ex.allPrepared() || ex.anyAborted() {
+		// Already settled one way or the other.
+		return address.Undef, big.Zero()
+	}
+
+	timedOut := rt.CurrEpoch() > ex.Params.Deadline+AtomicExecChallengePeriod
+	if !timedOut {
+		rt.Abortf(exitcode.ErrForbidden, "atomic execution challenge period hasn't elapsed yet")
+	}
+
+	for i := range ex.Status {
+		if ex.Status[i] == ExecInit {
+			ex.Status[i] = ExecAborted
+		}
+	}
+	refundTo, refund = st.settleAtomicExec(ex)
+	st.flushAtomicExec(rt, execCid, ex)
+	return refundTo, refund
+}
+
+// settleAtomicExec zeroes out ex.GasLocked once a proposal has reached a
+// terminal state and returns who it should be refunded to and how much,
+// for the caller to pay out via rt.Send outside any open state
+// transaction. It's idempotent: GasLocked is zeroed after the first call,
+// so a second settlement attempt returns a zero refund.
+func (st *SCAState) settleAtomicExec(ex *AtomicExec) (refundTo address.Address, refund abi.TokenAmount) {
+	if ex.GasLocked.IsZero() {
+		return address.Undef, big.Zero()
+	}
+	refund = ex.GasLocked
+	ex.GasLocked = big.Zero()
+	return ex.Initiator, refund
+}
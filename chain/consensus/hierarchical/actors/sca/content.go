@@ -0,0 +1,178 @@
+package sca
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	actor "github.com/filecoin-project/lotus/chain/consensus/actors"
+	"github.com/filecoin-project/specs-actors/v7/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v7/actors/runtime"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// ContentFeePerByteEpoch is the charge PublishContent takes out of its
+// ValueReceived, per byte of advertised Size and per epoch of availability.
+// TODO: Same caveat as MinSubnetStake: needs real economic analysis before
+// this is more than a placeholder.
+var ContentFeePerByteEpoch = big.NewInt(1)
+
+// ContentIndexEntry is the ContentIndex registry entry for a published
+// payload: which subnet holds it, how big it is, and until when it's
+// guaranteed available. Receivers of a CrossMsgMeta carrying a matching
+// PayloadCid use it to fetch the bytes off-band rather than trusting
+// whichever peer relayed the checkpoint.
+type ContentIndexEntry struct {
+	OwningSubnet address.SubnetID
+	Size         uint64
+	AvailUntil   abi.ChainEpoch
+}
+
+// getContentEntry loads the ContentIndex entry for payloadCid, if any.
+func (st *SCAState) getContentEntry(s adt.Store, payloadCid cid.Cid) (*ContentIndexEntry, bool, error) {
+	index, err := adt.AsMap(s, st.ContentIndex, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to load content index: %w", err)
+	}
+	var out ContentIndexEntry
+	found, err := index.Get(abi.CidKey(payloadCid), &out)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to get content index entry %s: %w", payloadCid, err)
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return &out, true, nil
+}
+
+// putContentEntry persists entry as the ContentIndex entry for payloadCid.
+func (st *SCAState) putContentEntry(rt runtime.Runtime, payloadCid cid.Cid, entry *ContentIndexEntry) {
+	index, err := adt.AsMap(adt.AsStore(rt), st.ContentIndex, builtin.DefaultHamtBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load content index")
+	err = index.Put(abi.CidKey(payloadCid), entry)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to put content index entry")
+	st.ContentIndex, err = index.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush content index")
+}
+
+// removeContentEntry deletes the ContentIndex entry for payloadCid.
+func (st *SCAState) removeContentEntry(rt runtime.Runtime, payloadCid cid.Cid) {
+	index, err := adt.AsMap(adt.AsStore(rt), st.ContentIndex, builtin.DefaultHamtBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load content index")
+	err = index.Delete(abi.CidKey(payloadCid))
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to remove content index entry")
+	st.ContentIndex, err = index.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush content index")
+}
+
+// ContentCidParams names a ContentIndex entry by its payload Cid.
+type ContentCidParams struct {
+	Cid string
+}
+
+// ResolveContent returns the ContentIndex entry for params.Cid, so a
+// receiver of a cross-message carrying a matching PayloadCid knows which
+// subnet to fetch the actual bytes from.
+func (a SubnetCoordActor) ResolveContent(rt runtime.Runtime, params *ContentCidParams) *ContentIndexEntry {
+	rt.ValidateImmediateCallerAcceptAny()
+	payloadCid, err := cid.Decode(params.Cid)
+	if err != nil {
+		rt.Abortf(exitcode.ErrIllegalArgument, "invalid content Cid: %s", err)
+	}
+
+	var st SCAState
+	rt.StateReadonly(&st)
+	entry, found, err := st.getContentEntry(adt.AsStore(rt), payloadCid)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load content index entry")
+	if !found {
+		rt.Abortf(exitcode.ErrIllegalArgument, "no content index entry for this Cid")
+	}
+	return entry
+}
+
+// PublishContentParams advertises a payload a subnet actor's checkpoint
+// references by Cid from one of its cross-messages.
+type PublishContentParams struct {
+	Cid      string
+	Size     uint64
+	Duration abi.ChainEpoch
+}
+
+// PublishContent registers (or renews) a ContentIndex entry for a payload
+// this subnet is making available for Duration epochs, charging a
+// per-byte-epoch fee out of ValueReceived. Only a subnet actor may publish,
+// and it's recorded as the owning subnet: that's who receivers fetch the
+// bytes from.
+func (a SubnetCoordActor) PublishContent(rt runtime.Runtime, params *PublishContentParams) *abi.EmptyValue {
+	rt.ValidateImmediateCallerType(actor.SubnetActorCodeID)
+	SubnetActorAddr := rt.Caller()
+
+	if params.Size == 0 {
+		rt.Abortf(exitcode.ErrIllegalArgument, "content size must be positive")
+	}
+	if params.Duration <= 0 {
+		rt.Abortf(exitcode.ErrIllegalArgument, "content availability duration must be positive")
+	}
+
+	payloadCid, err := cid.Decode(params.Cid)
+	if err != nil {
+		rt.Abortf(exitcode.ErrIllegalArgument, "invalid content Cid: %s", err)
+	}
+
+	fee := big.Mul(big.Mul(big.NewIntUnsigned(params.Size), big.NewInt(int64(params.Duration))), ContentFeePerByteEpoch)
+	value := rt.ValueReceived()
+	if value.LessThan(fee) {
+		rt.Abortf(exitcode.ErrIllegalArgument, "publishing this content requires a fee of %s, got %s", fee, value)
+	}
+	refund := big.Sub(value, fee)
+
+	var st SCAState
+	rt.StateTransaction(&st, func() {
+		shid := address.NewSubnetID(st.NetworkName, SubnetActorAddr)
+		entry := &ContentIndexEntry{
+			OwningSubnet: shid,
+			Size:         params.Size,
+			AvailUntil:   rt.CurrEpoch() + params.Duration,
+		}
+		st.putContentEntry(rt, payloadCid, entry)
+	})
+
+	code := rt.Send(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, fee, &builtin.Discard{})
+	if !code.IsSuccess() {
+		rt.Abortf(exitcode.ErrIllegalState, "failed burning content publication fee")
+	}
+	if refund.GreaterThan(big.Zero()) {
+		code = rt.Send(SubnetActorAddr, builtin.MethodSend, nil, refund, &builtin.Discard{})
+		if !code.IsSuccess() {
+			rt.Abortf(exitcode.ErrIllegalState, "failed refunding excess content publication fee")
+		}
+	}
+
+	return nil
+}
+
+// PruneContent is permissionless: once a ContentIndex entry's AvailUntil
+// has passed, anyone may call it to remove the now-stale entry.
+func (a SubnetCoordActor) PruneContent(rt runtime.Runtime, params *ContentCidParams) *abi.EmptyValue {
+	rt.ValidateImmediateCallerAcceptAny()
+	payloadCid, err := cid.Decode(params.Cid)
+	if err != nil {
+		rt.Abortf(exitcode.ErrIllegalArgument, "invalid content Cid: %s", err)
+	}
+
+	var st SCAState
+	rt.StateTransaction(&st, func() {
+		entry, found, err := st.getContentEntry(adt.AsStore(rt), payloadCid)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load content index entry")
+		if !found {
+			rt.Abortf(exitcode.ErrIllegalArgument, "no content index entry for this Cid")
+		}
+		if rt.CurrEpoch() <= entry.AvailUntil {
+			rt.Abortf(exitcode.ErrForbidden, "content entry hasn't expired yet")
+		}
+		st.removeContentEntry(rt, payloadCid)
+	})
+	return nil
+}
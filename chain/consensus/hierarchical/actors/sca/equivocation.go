@@ -0,0 +1,186 @@
+package sca
+
+import (
+	"bytes"
+
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/filecoin-project/specs-actors/v7/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v7/actors/runtime"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// EquivocationEvidence is the registry entry recorded for a slashed
+// equivocation, so the same pair of conflicting checkpoints can't be
+// reported (and bountied) twice. A and B are ordered by byte value rather
+// than by report order, so reporting the same pair either way around hits
+// the same entry.
+type EquivocationEvidence struct {
+	A cid.Cid
+	B cid.Cid
+}
+
+// newEquivocationEvidence orders a and b deterministically.
+func newEquivocationEvidence(a, b cid.Cid) *EquivocationEvidence {
+	if bytes.Compare(a.Bytes(), b.Bytes()) > 0 {
+		a, b = b, a
+	}
+	return &EquivocationEvidence{A: a, B: b}
+}
+
+// ReportCheckpointEquivocationParams names two conflicting checkpoints
+// signed for the same subnet and epoch, plus the index (into the subnet's
+// SignerSet) of a signer that contributed to both.
+type ReportCheckpointEquivocationParams struct {
+	CheckpointA []byte
+	CheckpointB []byte
+	SignerIdx   uint64
+}
+
+// ReportCheckpointEquivocation slashes a subnet whose registered signer set
+// produced two conflicting, validly-signed checkpoints for the same epoch.
+// Anyone may call it; the caller gets a bounty cut of the slashed stake for
+// doing so. If the slash leaves the subnet below st.MinStake, the subnet is
+// transitioned to Slashed, which blocks further CommitChildCheckpoint calls
+// until it's re-staked.
+func (a SubnetCoordActor) ReportCheckpointEquivocation(rt runtime.Runtime, params *ReportCheckpointEquivocationParams) *abi.EmptyValue {
+	// Anyone can report an equivocation; they're rewarded, not trusted.
+	rt.ValidateImmediateCallerAcceptAny()
+
+	chA := &schema.Checkpoint{}
+	err := chA.UnmarshalBinary(params.CheckpointA)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "error unmarshalling first checkpoint")
+	chB := &schema.Checkpoint{}
+	err = chB.UnmarshalBinary(params.CheckpointB)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "error unmarshalling second checkpoint")
+
+	if chA.Data.Source != chB.Data.Source {
+		rt.Abortf(exitcode.ErrIllegalArgument, "checkpoints don't belong to the same subnet")
+	}
+	if chA.Data.Epoch != chB.Data.Epoch {
+		rt.Abortf(exitcode.ErrIllegalArgument, "checkpoints don't belong to the same epoch")
+	}
+
+	source, err := hierarchical.SubnetID(chA.Data.Source).Actor()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "error getting checkpoint source")
+
+	cidA, err := chA.Cid()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "error computing first checkpoint's Cid")
+	cidB, err := chB.Cid()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "error computing second checkpoint's Cid")
+	if cidA == cidB {
+		rt.Abortf(exitcode.ErrIllegalArgument, "checkpoints are identical, not an equivocation")
+	}
+
+	var st SCAState
+	var bounty, burn abi.TokenAmount
+	rt.StateTransaction(&st, func() {
+		shid := hierarchical.NewSubnetID(st.NetworkName, source)
+		sh, has, err := st.GetSubnet(adt.AsStore(rt), shid)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "error fetching subnet state")
+		if !has {
+			rt.Abortf(exitcode.ErrIllegalArgument, "subnet for actor hasn't been registered yet")
+		}
+		if sh.Status == Killed {
+			rt.Abortf(exitcode.ErrIllegalState, "can't slash a subnet that has been killed")
+		}
+		if uint64(len(sh.SignerSet)) <= params.SignerIdx {
+			rt.Abortf(exitcode.ErrIllegalArgument, "signer index %d out of range for %d signers", params.SignerIdx, len(sh.SignerSet))
+		}
+		if err := requireSignerIncluded(chA, params.SignerIdx); err != nil {
+			rt.Abortf(exitcode.ErrIllegalArgument, "first checkpoint: %s", err)
+		}
+		if err := requireSignerIncluded(chB, params.SignerIdx); err != nil {
+			rt.Abortf(exitcode.ErrIllegalArgument, "second checkpoint: %s", err)
+		}
+		// Unlike the commit path, a slash must never skip verification: a
+		// subnet that hasn't onboarded a SignerSet threshold yet still has
+		// named signers, and SignerIdx must be shown to have actually
+		// signed both checkpoints before we punish it for that.
+		err = sh.VerifyCheckpointSignersStrict(CheckpointSigVerifier, cidA, chA)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "first checkpoint failed signer verification")
+		err = sh.VerifyCheckpointSignersStrict(CheckpointSigVerifier, cidB, chB)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "second checkpoint failed signer verification")
+
+		evidence := newEquivocationEvidence(cidA, cidB)
+		reported, err := st.reportedEquivocation(adt.AsStore(rt), evidence)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "error checking reported equivocations")
+		if reported {
+			rt.Abortf(exitcode.ErrForbidden, "this equivocation has already been reported")
+		}
+		st.recordReportedEquivocation(rt, evidence)
+
+		slash := big.Div(big.Mul(sh.Stake, big.NewInt(EquivocationSlashPercent)), big.NewInt(100))
+		bounty = big.Div(big.Mul(slash, big.NewInt(EquivocationBountyPercent)), big.NewInt(100))
+		burn = big.Sub(slash, bounty)
+
+		sh.addStake(rt, &st, slash.Neg())
+		if sh.Stake.LessThan(st.MinStake) {
+			sh.Status = Slashed
+		}
+		st.flushSubnet(rt, sh)
+	})
+
+	code := rt.Send(rt.Caller(), builtin.MethodSend, nil, bounty, &builtin.Discard{})
+	if !code.IsSuccess() {
+		rt.Abortf(exitcode.ErrIllegalState, "failed sending equivocation bounty to reporter")
+	}
+	code = rt.Send(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, burn, &builtin.Discard{})
+	if !code.IsSuccess() {
+		rt.Abortf(exitcode.ErrIllegalState, "failed burning the remainder of the slashed stake")
+	}
+
+	return nil
+}
+
+// requireSignerIncluded checks that idx is set in ch.Signers.
+func requireSignerIncluded(ch *schema.Checkpoint, idx uint64) error {
+	signers, err := bitfield.NewFromBytes(ch.Signers)
+	if err != nil {
+		return xerrors.Errorf("decoding signers bitfield: %w", err)
+	}
+	set, err := signers.IsSet(idx)
+	if err != nil {
+		return xerrors.Errorf("checking signers bitfield: %w", err)
+	}
+	if !set {
+		return xerrors.Errorf("named signer %d didn't contribute to this checkpoint's signature", idx)
+	}
+	return nil
+}
+
+// reportedEquivocation reports whether evidence has already been recorded.
+func (st *SCAState) reportedEquivocation(s adt.Store, evidence *EquivocationEvidence) (bool, error) {
+	evidenceCid, err := s.Put(s.Context(), evidence)
+	if err != nil {
+		return false, xerrors.Errorf("failed to compute evidence Cid: %w", err)
+	}
+	reports, err := adt.AsMap(s, st.ReportedEquivocations, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return false, xerrors.Errorf("failed to load reported equivocations: %w", err)
+	}
+	var out EquivocationEvidence
+	found, err := reports.Get(abi.CidKey(evidenceCid), &out)
+	if err != nil {
+		return false, xerrors.Errorf("failed to get reported equivocation: %w", err)
+	}
+	return found, nil
+}
+
+// recordReportedEquivocation persists evidence as already-reported.
+func (st *SCAState) recordReportedEquivocation(rt runtime.Runtime, evidence *EquivocationEvidence) {
+	evidenceCid, err := adt.AsStore(rt).Put(rt.Context(), evidence)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to compute evidence Cid")
+	reports, err := adt.AsMap(adt.AsStore(rt), st.ReportedEquivocations, builtin.DefaultHamtBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load reported equivocations")
+	err = reports.Put(abi.CidKey(evidenceCid), evidence)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to record reported equivocation")
+	st.ReportedEquivocations, err = reports.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush reported equivocations")
+}
@@ -0,0 +1,139 @@
+package sca
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	actor "github.com/filecoin-project/lotus/chain/consensus/actors"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/filecoin-project/specs-actors/v7/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v7/actors/runtime"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	"golang.org/x/xerrors"
+)
+
+// InitKill starts the two-phase shutdown of the calling subnet: it can no
+// longer be funded, but Release keeps working normally until UnlockEpoch so
+// outstanding circulating funds have a chance to drain out on their own
+// before FinalizeKill forces the rest back to their senders.
+func (a SubnetCoordActor) InitKill(rt runtime.Runtime, _ *abi.EmptyValue) *abi.EmptyValue {
+	rt.ValidateImmediateCallerType(actor.SubnetActorCodeID)
+	SubnetActorAddr := rt.Caller()
+
+	var st SCAState
+	rt.StateTransaction(&st, func() {
+		sh, has, err := st.getSubnetFromActorAddr(adt.AsStore(rt), SubnetActorAddr)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "error fetching subnet state")
+		if !has {
+			rt.Abortf(exitcode.ErrIllegalArgument, "subnet for actor hasn't been registered yet")
+		}
+		if sh.Status == Terminating || sh.Status == Killed {
+			rt.Abortf(exitcode.ErrIllegalState, "subnet is already being killed")
+		}
+
+		sh.Status = Terminating
+		sh.UnlockEpoch = rt.CurrEpoch() + KillDrainPeriod
+		// Snapshot the pending fund injections as of right now: anything
+		// funded after this can't happen (Fund rejects Terminating
+		// subnets), and anything released before FinalizeKill runs just
+		// means that funder's entry here goes unrefunded because they
+		// already got their funds back the ordinary way.
+		sh.RefundQueue = sh.TopDownMsgs
+		sh.RefundTotal = sh.FundMsgCount
+		sh.RefundCursor = 0
+		st.flushSubnet(rt, sh)
+	})
+	return nil
+}
+
+// FinalizeKill is permissionless: once a Terminating subnet's drain window
+// has passed, anyone may call it to push its refund queue forward by up to
+// RefundChunkSize entries, bundling one refund schema.CrossMsgMeta per
+// pending injection into the current-window checkpoint. Once the queue is
+// fully drained, it deletes the subnet and returns its remaining stake to
+// the subnet actor.
+func (a SubnetCoordActor) FinalizeKill(rt runtime.Runtime, params *SubnetIDParam) *abi.EmptyValue {
+	rt.ValidateImmediateCallerAcceptAny()
+
+	var st SCAState
+	var drained bool
+	var remainingStake abi.TokenAmount
+	var subnetActorAddr address.Address
+	rt.StateTransaction(&st, func() {
+		shid := hierarchical.SubnetID(params.ID)
+		sh, has, err := st.GetSubnet(adt.AsStore(rt), shid)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "error fetching subnet state")
+		if !has {
+			rt.Abortf(exitcode.ErrIllegalArgument, "subnet hasn't been registered")
+		}
+		if sh.Status != Terminating {
+			rt.Abortf(exitcode.ErrIllegalState, "FinalizeKill can only run on a subnet that called InitKill")
+		}
+		if rt.CurrEpoch() < sh.UnlockEpoch {
+			rt.Abortf(exitcode.ErrForbidden, "subnet's drain window hasn't elapsed yet")
+		}
+
+		refunds := sh.nextRefundChunk(rt)
+		ch := st.currWindowCheckpoint(rt)
+		st.aggChildMsgMeta(rt, ch, map[string][]schema.CrossMsgMeta{sh.ParentID.String(): refunds})
+		st.flushCheckpoint(rt, ch)
+
+		if sh.RefundCursor < sh.RefundTotal {
+			st.flushSubnet(rt, sh)
+			return
+		}
+
+		// Fully drained: remove the subnet for good.
+		subnetActorAddr, err = shid.Actor()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "error getting subnet actor address")
+		remainingStake = sh.Stake
+		drained = true
+
+		subnets, err := adt.AsMap(adt.AsStore(rt), st.Subnets, builtin.DefaultHamtBitwidth)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load state for subnets")
+		err = subnets.Delete(shid)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to remove subnet from registry")
+		st.Subnets, err = subnets.Root()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush subnets after removal")
+	})
+
+	if drained {
+		code := rt.Send(subnetActorAddr, builtin.MethodSend, nil, remainingStake, &builtin.Discard{})
+		if !code.IsSuccess() {
+			rt.Abortf(exitcode.ErrIllegalState, "failed sending released stake to subnet actor")
+		}
+	}
+
+	return nil
+}
+
+// nextRefundChunk advances sh.RefundCursor by up to RefundChunkSize entries
+// of sh.RefundQueue, returning one refund schema.CrossMsgMeta per pending
+// fund injection it visited, carrying the original Value and Nonce back to
+// the funder so the unlocked funds actually make it home.
+func (sh *Subnet) nextRefundChunk(rt runtime.Runtime) []schema.CrossMsgMeta {
+	arr, err := adt.AsArray(adt.AsStore(rt), sh.RefundQueue, CrossMsgsAMTBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load refund queue")
+
+	refunds := make([]schema.CrossMsgMeta, 0, RefundChunkSize)
+	stop := xerrors.New("stop")
+	var fm FundMsg
+	var seen uint64
+	err = arr.ForEach(&fm, func(i int64) error {
+		defer func() { seen++ }()
+		if seen < sh.RefundCursor {
+			return nil
+		}
+		refunds = append(refunds, schema.CrossMsgMeta{To: fm.To.String(), Nonce: fm.Nonce, Value: fm.Value})
+		sh.RefundCursor++
+		if uint64(len(refunds)) >= RefundChunkSize {
+			return stop
+		}
+		return nil
+	})
+	if err != nil && err != stop {
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to iterate refund queue")
+	}
+	return refunds
+}
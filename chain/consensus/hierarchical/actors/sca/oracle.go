@@ -0,0 +1,136 @@
+package sca
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/filecoin-project/specs-actors/v7/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v7/actors/runtime"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	cid "github.com/ipfs/go-cid"
+)
+
+// CheckpointOracle is the network-wide committee attesting to this SCA's
+// own checkpoints, as an alternative to relying solely on miner consensus:
+// a light client (or a child subnet) that trusts the committee can accept
+// a checkpoint by verifying Signature/Signers against Epoch's signer set
+// alone, without replaying this chain. Mirrors Subnet's SignerSet/
+// SignerThreshold, scoped to the whole network instead of one subnet.
+type CheckpointOracle struct {
+	Signers   []SubnetSigner
+	Threshold big.Int
+	// Epoch is incremented by every UpdateOracleSigners call. It's embedded
+	// as CheckData.OracleEpoch in every checkpoint SubmitSignedCheckpoint
+	// accepts from here on, so a verifier knows which historical signer
+	// set (not necessarily the current one) to check an old checkpoint
+	// against.
+	Epoch uint64
+}
+
+// verifySigners checks commit's aggregate signature against o's current
+// signer set and threshold. If Threshold is zero (no committee has been
+// onboarded via UpdateOracleSigners yet), it's a no-op: there's no
+// committee to verify against.
+func (o *CheckpointOracle) verifySigners(v BLSAggregateVerifier, checkCid cid.Cid, commit *schema.Checkpoint) error {
+	if o.Threshold.LessThanEqual(big.Zero()) {
+		return nil
+	}
+	return verifyAggregateCheckpointSig(v, checkCid, commit, o.Signers, o.Threshold)
+}
+
+// UpdateOracleSignersParams replaces the CheckpointOracle's committee and
+// threshold. Sig/Signers authenticate the change against the outgoing
+// committee (required for every rotation after the first); they're
+// verified over the Cid of the proposed CheckpointOracle itself.
+type UpdateOracleSignersParams struct {
+	Signers   []SignerParams
+	Threshold abi.TokenAmount
+	Sig       []byte
+	Bitmap    []byte
+}
+
+// UpdateOracleSigners onboards or rotates the CheckpointOracle's committee.
+// The very first call (Epoch 0, no committee yet) needs no signature: there
+// is no prior committee to attest to the change. Every later rotation must
+// carry a valid aggregate signature, contributed by the *outgoing*
+// committee, over the Cid of the proposed new CheckpointOracle - so a
+// compromised caller can't unilaterally replace the committee.
+func (a SubnetCoordActor) UpdateOracleSigners(rt runtime.Runtime, params *UpdateOracleSignersParams) *abi.EmptyValue {
+	rt.ValidateImmediateCallerAcceptAny()
+
+	signers := make([]SubnetSigner, len(params.Signers))
+	for i, s := range params.Signers {
+		addr, err := address.NewFromString(s.Addr)
+		if err != nil {
+			rt.Abortf(exitcode.ErrIllegalArgument, "invalid signer address %s: %s", s.Addr, err)
+		}
+		signers[i] = SubnetSigner{Addr: addr, Weight: s.Weight}
+	}
+	if params.Threshold.LessThan(big.Zero()) {
+		rt.Abortf(exitcode.ErrIllegalArgument, "oracle signer threshold can't be negative")
+	}
+
+	var st SCAState
+	rt.StateTransaction(&st, func() {
+		proposed := CheckpointOracle{Signers: signers, Threshold: params.Threshold, Epoch: st.CheckpointOracle.Epoch + 1}
+
+		if st.CheckpointOracle.Epoch > 0 {
+			if CheckpointSigVerifier == nil {
+				rt.Abortf(exitcode.ErrIllegalState, "no BLS aggregate verifier configured for this node")
+			}
+			proposedCid, err := adt.AsStore(rt).Put(rt.Context(), &proposed)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to compute Cid of proposed oracle committee")
+			commit := &schema.Checkpoint{Signature: params.Sig, Signers: params.Bitmap}
+			err = st.CheckpointOracle.verifySigners(CheckpointSigVerifier, proposedCid, commit)
+			if err != nil {
+				rt.Abortf(exitcode.ErrForbidden, "oracle committee rotation rejected: %s", err)
+			}
+		}
+
+		st.CheckpointOracle = proposed
+	})
+	return nil
+}
+
+// SubmitSignedCheckpointParams carries a checkpoint already populated and
+// attested to by the current CheckpointOracle committee.
+type SubmitSignedCheckpointParams struct {
+	Checkpoint schema.Checkpoint
+}
+
+// SubmitSignedCheckpoint lets the oracle committee commit a checkpoint for
+// this SCA's own current window directly, as an alternative path to the
+// ordinary miner-driven consensus flow: once enough of the committee (by
+// weight) has signed it, it's accepted and flushed without further
+// on-chain validation. Data.OracleEpoch must match the committee epoch
+// that's meant to have signed it, so a checkpoint can't be replayed
+// against a committee it was never actually attested by.
+func (a SubnetCoordActor) SubmitSignedCheckpoint(rt runtime.Runtime, params *SubmitSignedCheckpointParams) *abi.EmptyValue {
+	rt.ValidateImmediateCallerAcceptAny()
+	if CheckpointSigVerifier == nil {
+		rt.Abortf(exitcode.ErrIllegalState, "no BLS aggregate verifier configured for this node")
+	}
+	commit := &params.Checkpoint
+
+	var st SCAState
+	rt.StateTransaction(&st, func() {
+		if st.CheckpointOracle.Threshold.LessThanEqual(big.Zero()) {
+			rt.Abortf(exitcode.ErrForbidden, "no oracle committee has been onboarded yet")
+		}
+		if uint64(commit.Data.OracleEpoch) != st.CheckpointOracle.Epoch {
+			rt.Abortf(exitcode.ErrIllegalArgument, "checkpoint signed for oracle epoch %d, current epoch is %d", commit.Data.OracleEpoch, st.CheckpointOracle.Epoch)
+		}
+
+		checkCid, err := commit.Cid()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "error computing checkpoint Cid")
+		err = st.CheckpointOracle.verifySigners(CheckpointSigVerifier, checkCid, commit)
+		if err != nil {
+			rt.Abortf(exitcode.ErrForbidden, "checkpoint rejected: %s", err)
+		}
+
+		st.flushCheckpoint(rt, commit)
+	})
+	return nil
+}
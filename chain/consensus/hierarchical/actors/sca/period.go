@@ -0,0 +1,291 @@
+package sca
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/filecoin-project/specs-actors/v7/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v7/actors/runtime"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// PeriodHistoryAMTBitwidth sizes the AMT backing a SCA's CheckPeriod
+	// history. Period changes are rare governance/adaptive events rather
+	// than a per-epoch structure, so this matches CrossMsgsAMTBitwidth
+	// instead of the wider default.
+	PeriodHistoryAMTBitwidth = CrossMsgsAMTBitwidth
+
+	// CheckPeriodQuorumPercent is the share of total registered-subnet
+	// Stake a ProposeCheckPeriod proposal needs behind it via
+	// VoteCheckPeriod before it takes effect.
+	CheckPeriodQuorumPercent = 66
+
+	// AdaptiveMinChilds/AdaptiveMaxChilds bound how many child checkpoints
+	// a window aggregated (see schema.CheckData.Childs) before the
+	// adaptive mode considers it near-empty or busy enough to rescale
+	// CheckPeriod. TODO: same caveat as MinSubnetStake - these need real
+	// empirical tuning, not just enough to express the policy.
+	AdaptiveMinChilds = 1
+	AdaptiveMaxChilds = 8
+
+	// AdaptivePeriodStep is how far CheckPeriod moves per adaptive
+	// adjustment, bounded to [MinCheckpointPeriod, AdaptivePeriodMax].
+	AdaptivePeriodStep = abi.ChainEpoch(5)
+	AdaptivePeriodMax  = abi.ChainEpoch(200)
+)
+
+// PeriodHistoryEntry records that, from FromEpoch onward (until the next
+// entry's FromEpoch), a SCA's checkpoint windows were Period epochs wide.
+// CurrWindowCheckpoint/RawCheckpoint consult this history instead of
+// st.CheckPeriod directly, so windows that predate a ProposeCheckPeriod/
+// VoteCheckPeriod switchover (or an adaptive adjustment) still bucket to
+// the period they were actually computed under.
+type PeriodHistoryEntry struct {
+	FromEpoch abi.ChainEpoch
+	Period    abi.ChainEpoch
+}
+
+// periodAt returns the CheckPeriod in effect at epoch: the Period of the
+// latest history entry whose FromEpoch <= epoch, or DefaultCheckpointPeriod
+// if historyRoot is empty (shouldn't happen past construction, but keeps
+// this total rather than erroring on an empty history).
+func periodAt(s adt.Store, historyRoot cid.Cid, epoch abi.ChainEpoch) (abi.ChainEpoch, error) {
+	arr, err := adt.AsArray(s, historyRoot, PeriodHistoryAMTBitwidth)
+	if err != nil {
+		return 0, xerrors.Errorf("failed to load period history: %w", err)
+	}
+
+	period := DefaultCheckpointPeriod
+	var e PeriodHistoryEntry
+	for i := uint64(0); i < arr.Length(); i++ {
+		found, err := arr.Get(i, &e)
+		if err != nil {
+			return 0, xerrors.Errorf("failed to get period history entry %d: %w", i, err)
+		}
+		if !found {
+			continue
+		}
+		if e.FromEpoch > epoch {
+			break
+		}
+		period = e.Period
+	}
+	return period, nil
+}
+
+// appendPeriodHistory records that, from fromEpoch onward, period applies,
+// and returns the new history root. Entries are appended in increasing
+// FromEpoch order - the only order ProposeCheckPeriod/adaptive adjustments
+// produce them in - so periodAt's linear scan can stop as soon as it
+// passes epoch.
+func appendPeriodHistory(rt runtime.Runtime, historyRoot cid.Cid, fromEpoch, period abi.ChainEpoch) cid.Cid {
+	arr, err := adt.AsArray(adt.AsStore(rt), historyRoot, PeriodHistoryAMTBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load period history")
+	err = arr.Set(arr.Length(), &PeriodHistoryEntry{FromEpoch: fromEpoch, Period: period})
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to append period history entry")
+	root, err := arr.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush period history")
+	return root
+}
+
+// totalSubnetStake sums Stake across every subnet registered with st,
+// the denominator ProposeCheckPeriod/VoteCheckPeriod weigh votes against.
+func (st *SCAState) totalSubnetStake(s adt.Store) (big.Int, error) {
+	subnets, err := adt.AsMap(s, st.Subnets, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return big.Zero(), xerrors.Errorf("failed to load subnets: %w", err)
+	}
+	total := big.Zero()
+	var sh Subnet
+	err = subnets.ForEach(&sh, func(k string) error {
+		total = big.Add(total, sh.Stake)
+		return nil
+	})
+	if err != nil {
+		return big.Zero(), xerrors.Errorf("failed to tally subnet stake: %w", err)
+	}
+	return total, nil
+}
+
+// CheckPeriodProposal is a pending governance proposal to change a SCA's
+// CheckPeriod, keyed by the Cid of its own fields in
+// SCAState.CheckPeriodProposals. Registered child subnets vote with
+// VoteCheckPeriod, weighted by their own Subnet.Stake - the same notion of
+// "stake" that already gates subnet registration in Register/AddStake -
+// until Voted crosses CheckPeriodQuorumPercent of TotalStake.
+type CheckPeriodProposal struct {
+	Period     abi.ChainEpoch
+	TotalStake big.Int
+	Voted      big.Int
+	// Voters records, per voting subnet, the Stake weight it cast, keyed
+	// by hierarchical.SubnetKey(subnet ID), so VoteCheckPeriod can reject
+	// a subnet voting on the same proposal twice.
+	Voters cid.Cid // HAMT[subnet ID]big.Int
+}
+
+// ProposeCheckPeriodParams is the argument to ProposeCheckPeriod.
+type ProposeCheckPeriodParams struct {
+	Period abi.ChainEpoch
+}
+
+// CheckPeriodProposalIDParam names a CheckPeriodProposal by its Cid, the
+// id VoteCheckPeriod expects back in its own params.
+type CheckPeriodProposalIDParam struct {
+	Cid string
+}
+
+// ProposeCheckPeriod opens a governance vote to change the SCA's
+// CheckPeriod to params.Period once it crosses quorum, and returns the
+// proposal's id for use with VoteCheckPeriod. Any caller may propose;
+// VoteCheckPeriod is what actually requires a registered child subnet's
+// stake behind it.
+func (a SubnetCoordActor) ProposeCheckPeriod(rt runtime.Runtime, params *ProposeCheckPeriodParams) *CheckPeriodProposalIDParam {
+	rt.ValidateImmediateCallerAcceptAny()
+	if params.Period < MinCheckpointPeriod {
+		rt.Abortf(exitcode.ErrIllegalArgument, "proposed check period %d below minimum %d", params.Period, MinCheckpointPeriod)
+	}
+
+	var st SCAState
+	var proposalCid cid.Cid
+	rt.StateTransaction(&st, func() {
+		emptyVoters, err := adt.StoreEmptyMap(adt.AsStore(rt), builtin.DefaultHamtBitwidth)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to create empty voters map")
+
+		totalStake, err := st.totalSubnetStake(adt.AsStore(rt))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to tally total subnet stake")
+		if totalStake.LessThanEqual(big.Zero()) {
+			rt.Abortf(exitcode.ErrForbidden, "no registered subnets to form a quorum with")
+		}
+
+		proposal := &CheckPeriodProposal{Period: params.Period, TotalStake: totalStake, Voted: big.Zero(), Voters: emptyVoters}
+		c, err := adt.AsStore(rt).Put(rt.Context(), proposal)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to compute proposal Cid")
+		proposalCid = c
+
+		proposals, err := adt.AsMap(adt.AsStore(rt), st.CheckPeriodProposals, builtin.DefaultHamtBitwidth)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load period proposals")
+		err = proposals.Put(abi.CidKey(proposalCid), proposal)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to put period proposal")
+		st.CheckPeriodProposals, err = proposals.Root()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush period proposals")
+	})
+	return &CheckPeriodProposalIDParam{Cid: proposalCid.String()}
+}
+
+// VoteCheckPeriodParams is the argument to VoteCheckPeriod.
+type VoteCheckPeriodParams struct {
+	Proposal string
+}
+
+// VoteCheckPeriod registers the caller's child subnet's vote, weighted by
+// its Stake, for the proposal named by params.Proposal. Once accumulated
+// Voted crosses CheckPeriodQuorumPercent of the proposal's TotalStake, it
+// takes effect immediately: a new PeriodHistoryEntry is appended from the
+// next epoch, CheckPeriod is updated, and the proposal is removed.
+func (a SubnetCoordActor) VoteCheckPeriod(rt runtime.Runtime, params *VoteCheckPeriodParams) *abi.EmptyValue {
+	rt.ValidateImmediateCallerAcceptAny()
+	proposalCid, err := cid.Decode(params.Proposal)
+	if err != nil {
+		rt.Abortf(exitcode.ErrIllegalArgument, "invalid proposal id %s: %s", params.Proposal, err)
+	}
+
+	var st SCAState
+	rt.StateTransaction(&st, func() {
+		sh, has, err := st.getSubnetFromActorAddr(adt.AsStore(rt), rt.Caller())
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to look up caller's subnet")
+		if !has {
+			rt.Abortf(exitcode.ErrForbidden, "caller isn't a registered child subnet")
+		}
+
+		proposals, err := adt.AsMap(adt.AsStore(rt), st.CheckPeriodProposals, builtin.DefaultHamtBitwidth)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load period proposals")
+		var proposal CheckPeriodProposal
+		found, err := proposals.Get(abi.CidKey(proposalCid), &proposal)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to get period proposal")
+		if !found {
+			rt.Abortf(exitcode.ErrIllegalArgument, "no such period proposal %s", params.Proposal)
+		}
+
+		voters, err := adt.AsMap(adt.AsStore(rt), proposal.Voters, builtin.DefaultHamtBitwidth)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load proposal voters")
+		var existingVote big.Int
+		alreadyVoted, err := voters.Get(hierarchical.SubnetKey(sh.ID), &existingVote)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check existing vote")
+		if alreadyVoted {
+			rt.Abortf(exitcode.ErrForbidden, "subnet %s has already voted on this proposal", sh.ID)
+		}
+		err = voters.Put(hierarchical.SubnetKey(sh.ID), &sh.Stake)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to record vote")
+		proposal.Voters, err = voters.Root()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush proposal voters")
+		proposal.Voted = big.Add(proposal.Voted, sh.Stake)
+
+		if !crossesQuorum(proposal.Voted, proposal.TotalStake) {
+			proposals, err := adt.AsMap(adt.AsStore(rt), st.CheckPeriodProposals, builtin.DefaultHamtBitwidth)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load period proposals")
+			err = proposals.Put(abi.CidKey(proposalCid), &proposal)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to put updated period proposal")
+			st.CheckPeriodProposals, err = proposals.Root()
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush period proposals")
+			return
+		}
+
+		st.PeriodHistory = appendPeriodHistory(rt, st.PeriodHistory, rt.CurrEpoch()+1, proposal.Period)
+		st.CheckPeriod = proposal.Period
+
+		proposalsMap, err := adt.AsMap(adt.AsStore(rt), st.CheckPeriodProposals, builtin.DefaultHamtBitwidth)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load period proposals")
+		err = proposalsMap.Delete(abi.CidKey(proposalCid))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to remove finalized period proposal")
+		st.CheckPeriodProposals, err = proposalsMap.Root()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush period proposals")
+	})
+	return nil
+}
+
+// crossesQuorum reports whether voted*100 >= total*CheckPeriodQuorumPercent.
+func crossesQuorum(voted, total big.Int) bool {
+	lhs := big.Mul(voted, big.NewInt(100))
+	rhs := big.Mul(total, big.NewInt(CheckPeriodQuorumPercent))
+	return lhs.GreaterThanEqual(rhs)
+}
+
+// adjustAdaptivePeriod runs the automatic scaling mode: if st.AdaptivePeriod
+// is set, it grows CheckPeriod when ch aggregated AdaptiveMinChilds or
+// fewer child checkpoints (a near-empty window, not worth checkpointing
+// this often) and shrinks it when ch aggregated more than AdaptiveMaxChilds
+// (CheckMsgsRegistry growth outpacing the window), recording the change in
+// PeriodHistory the same way a governance vote would. A no-op when
+// AdaptivePeriod is false, or when the observed load doesn't cross either
+// threshold.
+func (st *SCAState) adjustAdaptivePeriod(rt runtime.Runtime, ch *schema.Checkpoint) {
+	if !st.AdaptivePeriod {
+		return
+	}
+
+	childs := len(ch.Data.Childs)
+	next := st.CheckPeriod
+	switch {
+	case childs <= AdaptiveMinChilds:
+		next = st.CheckPeriod + AdaptivePeriodStep
+		if next > AdaptivePeriodMax {
+			next = AdaptivePeriodMax
+		}
+	case childs > AdaptiveMaxChilds:
+		next = st.CheckPeriod - AdaptivePeriodStep
+		if next < MinCheckpointPeriod {
+			next = MinCheckpointPeriod
+		}
+	}
+
+	if next == st.CheckPeriod {
+		return
+	}
+	st.PeriodHistory = appendPeriodHistory(rt, st.PeriodHistory, rt.CurrEpoch()+1, next)
+	st.CheckPeriod = next
+}
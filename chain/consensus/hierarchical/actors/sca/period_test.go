@@ -0,0 +1,64 @@
+package sca
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/require"
+)
+
+func newPeriodTestStore(t *testing.T) adt.Store {
+	t.Helper()
+	bs := blockstore.NewMemorySync()
+	return adt.WrapStore(context.Background(), cbor.NewCborStore(bs))
+}
+
+func TestPeriodAtUsesLatestEntryNotAfterEpoch(t *testing.T) {
+	store := newPeriodTestStore(t)
+	root, err := adt.StoreEmptyArray(store, PeriodHistoryAMTBitwidth)
+	require.NoError(t, err)
+
+	arr, err := adt.AsArray(store, root, PeriodHistoryAMTBitwidth)
+	require.NoError(t, err)
+	require.NoError(t, arr.Set(0, &PeriodHistoryEntry{FromEpoch: 0, Period: 10}))
+	require.NoError(t, arr.Set(1, &PeriodHistoryEntry{FromEpoch: 100, Period: 20}))
+	root, err = arr.Root()
+	require.NoError(t, err)
+
+	p, err := periodAt(store, root, 0)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, p)
+
+	p, err = periodAt(store, root, 99)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, p)
+
+	p, err = periodAt(store, root, 100)
+	require.NoError(t, err)
+	require.EqualValues(t, 20, p)
+
+	p, err = periodAt(store, root, 1000)
+	require.NoError(t, err)
+	require.EqualValues(t, 20, p)
+}
+
+func TestCrossesQuorum(t *testing.T) {
+	total := big.NewInt(100)
+	require.False(t, crossesQuorum(big.NewInt(65), total))
+	require.True(t, crossesQuorum(big.NewInt(66), total))
+	require.True(t, crossesQuorum(big.NewInt(100), total))
+}
+
+func TestTotalSubnetStakeIsZeroWithoutSubnets(t *testing.T) {
+	store := newPeriodTestStore(t)
+	st, err := ConstructSCAState(store, &ConstructorParams{NetworkName: "/root", CheckpointPeriod: uint64(DefaultCheckpointPeriod)})
+	require.NoError(t, err)
+
+	total, err := st.totalSubnetStake(store)
+	require.NoError(t, err)
+	require.True(t, total.IsZero())
+}
@@ -0,0 +1,213 @@
+package sca
+
+import (
+	"strings"
+
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/filecoin-project/specs-actors/v7/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v7/actors/runtime"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// PostboxItem is a cross-message parked at this SCA because applyCheckMsgs
+// couldn't resolve it to either the existing top-down path (a registered
+// child subtree) or the existing up-going path (aggregating into this
+// SCA's own outgoing checkpoint). Owners names who may call PropagateMsg to
+// move it another hop; it starts as just the checkpoint's source subnet.
+type PostboxItem struct {
+	Msg    schema.CrossMsgMeta
+	Owners []string
+}
+
+// postboxMsgCid content-addresses mm so it can key the Postbox HAMT.
+func postboxMsgCid(s adt.Store, mm schema.CrossMsgMeta) (cid.Cid, error) {
+	return s.Put(s.Context(), &mm)
+}
+
+// getPostboxItem loads the Postbox entry for msgCid, if any.
+func (st *SCAState) getPostboxItem(s adt.Store, msgCid cid.Cid) (*PostboxItem, bool, error) {
+	postbox, err := adt.AsMap(s, st.Postbox, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to load postbox: %w", err)
+	}
+	var out PostboxItem
+	found, err := postbox.Get(abi.CidKey(msgCid), &out)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to get postbox item %s: %w", msgCid, err)
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return &out, true, nil
+}
+
+// putPostboxItem persists item as the Postbox entry for msgCid.
+func (st *SCAState) putPostboxItem(rt runtime.Runtime, msgCid cid.Cid, item *PostboxItem) {
+	postbox, err := adt.AsMap(adt.AsStore(rt), st.Postbox, builtin.DefaultHamtBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load postbox")
+	err = postbox.Put(abi.CidKey(msgCid), item)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to put postbox item")
+	st.Postbox, err = postbox.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush postbox")
+}
+
+// removePostboxItem deletes the Postbox entry for msgCid, once it's been
+// propagated another hop out of this SCA.
+func (st *SCAState) removePostboxItem(rt runtime.Runtime, msgCid cid.Cid) {
+	postbox, err := adt.AsMap(adt.AsStore(rt), st.Postbox, builtin.DefaultHamtBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load postbox")
+	err = postbox.Delete(abi.CidKey(msgCid))
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to remove postbox item")
+	st.Postbox, err = postbox.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush postbox")
+}
+
+// storePostboxMsg parks mm in the Postbox, owned initially by owner, and
+// returns its key Cid. Calling it again for the same mm just adds owner to
+// the existing entry's Owners instead of duplicating it.
+func (st *SCAState) storePostboxMsg(rt runtime.Runtime, mm schema.CrossMsgMeta, owner string) cid.Cid {
+	msgCid, err := postboxMsgCid(adt.AsStore(rt), mm)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to compute postbox msg Cid")
+
+	item, found, err := st.getPostboxItem(adt.AsStore(rt), msgCid)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check for existing postbox item")
+	if !found {
+		item = &PostboxItem{Msg: mm, Owners: []string{owner}}
+	} else if !hasOwner(item.Owners, owner) {
+		item.Owners = append(item.Owners, owner)
+	}
+	st.putPostboxItem(rt, msgCid, item)
+	return msgCid
+}
+
+func hasOwner(owners []string, who string) bool {
+	for _, o := range owners {
+		if o == who {
+			return true
+		}
+	}
+	return false
+}
+
+// childHop reports whether dest is a descendant of current, and if so,
+// which of current's immediate children dest falls under.
+//
+// hierarchical.SubnetID is a "/"-delimited path of actor addresses (see its
+// use via SubnetID.Actor() elsewhere in this package), so routing between
+// two subnet paths is just prefix arithmetic over their string form; it
+// doesn't need a method on the hierarchical package itself.
+func childHop(current, dest string) (child string, ok bool) {
+	current = strings.TrimSuffix(current, "/")
+	if dest == current {
+		return "", false
+	}
+	prefix := current + "/"
+	if !strings.HasPrefix(dest, prefix) {
+		return "", false
+	}
+	rest := strings.TrimPrefix(dest, prefix)
+	seg := strings.SplitN(rest, "/", 2)[0]
+	return prefix + seg, true
+}
+
+// isAncestor reports whether dest is current or a strict ancestor of it,
+// i.e. the message is genuinely headed further up the hierarchy rather
+// than into a sibling subtree this SCA can't directly route to.
+func isAncestor(current, dest string) bool {
+	current = strings.TrimSuffix(current, "/")
+	dest = strings.TrimSuffix(dest, "/")
+	return current == dest || strings.HasPrefix(current, dest+"/")
+}
+
+// PostboxParams names a Postbox entry by the Cid of its schema.CrossMsgMeta.
+type PostboxParams struct {
+	Cid string
+}
+
+// Postbox returns the entry parked for params.Cid, if any.
+func (a SubnetCoordActor) Postbox(rt runtime.Runtime, params *PostboxParams) *PostboxItem {
+	rt.ValidateImmediateCallerAcceptAny()
+	msgCid, err := cid.Decode(params.Cid)
+	if err != nil {
+		rt.Abortf(exitcode.ErrIllegalArgument, "invalid postbox Cid: %s", err)
+	}
+
+	var st SCAState
+	rt.StateReadonly(&st)
+	item, found, err := st.getPostboxItem(adt.AsStore(rt), msgCid)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load postbox item")
+	if !found {
+		rt.Abortf(exitcode.ErrIllegalArgument, "no postbox entry for this Cid")
+	}
+	return item
+}
+
+// PropagateMsg lets one of a Postbox entry's owners pay to move it another
+// hop: down into a registered child subtree's top-down queue if the
+// destination lives under this SCA, or up into this SCA's own outgoing
+// checkpoint otherwise. The entry is removed from the Postbox either way;
+// if it's still not resolvable (e.g. the child subnet it needs was killed
+// since it was parked), it's re-parked rather than dropped.
+func (a SubnetCoordActor) PropagateMsg(rt runtime.Runtime, params *PostboxParams) *abi.EmptyValue {
+	rt.ValidateImmediateCallerAcceptAny()
+	caller := rt.Caller()
+
+	value := rt.ValueReceived()
+	if value.LessThanEqual(big.NewInt(0)) {
+		rt.Abortf(exitcode.ErrIllegalArgument, "propagating a message requires paying its forwarding gas")
+	}
+
+	msgCid, err := cid.Decode(params.Cid)
+	if err != nil {
+		rt.Abortf(exitcode.ErrIllegalArgument, "invalid postbox Cid: %s", err)
+	}
+
+	var st SCAState
+	rt.StateTransaction(&st, func() {
+		item, found, err := st.getPostboxItem(adt.AsStore(rt), msgCid)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load postbox item")
+		if !found {
+			rt.Abortf(exitcode.ErrIllegalArgument, "no postbox entry for this Cid")
+		}
+		if !hasOwner(item.Owners, caller.String()) {
+			rt.Abortf(exitcode.ErrForbidden, "caller is not an owner of this postbox entry")
+		}
+
+		if child, ok := childHop(st.NetworkName.String(), item.Msg.To); ok {
+			childSh, has, err := st.GetSubnet(adt.AsStore(rt), address.SubnetID(child))
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load child subnet")
+			if !has {
+				// The child this message needs was killed/never existed;
+				// leave it parked rather than dropping it on the floor.
+				return
+			}
+			childSh.addTopDownCrossMsg(rt, &st, item.Msg)
+			st.removePostboxItem(rt, msgCid)
+			return
+		}
+
+		if isAncestor(st.NetworkName.String(), item.Msg.To) {
+			ch := st.currWindowCheckpoint(rt)
+			st.aggChildMsgMeta(rt, ch, map[string][]schema.CrossMsgMeta{item.Msg.To: {item.Msg}})
+			st.flushCheckpoint(rt, ch)
+			st.removePostboxItem(rt, msgCid)
+			return
+		}
+
+		// Still neither a resolvable child subtree nor an ancestor path;
+		// stays parked for another owner/attempt to pick up later.
+	})
+
+	code := rt.Send(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, value, &builtin.Discard{})
+	if !code.IsSuccess() {
+		rt.Abortf(exitcode.ErrIllegalState, "failed paying forwarding gas for propagated message")
+	}
+
+	return nil
+}
@@ -31,15 +31,31 @@ var SubnetCoordActorAddr = func() address.Address {
 }()
 
 var Methods = struct {
-	Constructor           abi.MethodNum
-	Register              abi.MethodNum
-	AddStake              abi.MethodNum
-	ReleaseStake          abi.MethodNum
-	Kill                  abi.MethodNum
-	CommitChildCheckpoint abi.MethodNum
-	Fund                  abi.MethodNum
-	Release               abi.MethodNum
-}{builtin0.MethodConstructor, 2, 3, 4, 5, 6, 7, 8}
+	Constructor                  abi.MethodNum
+	Register                     abi.MethodNum
+	AddStake                     abi.MethodNum
+	ReleaseStake                 abi.MethodNum
+	Kill                         abi.MethodNum
+	CommitChildCheckpoint        abi.MethodNum
+	Fund                         abi.MethodNum
+	Release                      abi.MethodNum
+	InitAtomicExec               abi.MethodNum
+	SubmitAtomicExec             abi.MethodNum
+	AbortAtomicExec              abi.MethodNum
+	UpdateSigners                abi.MethodNum
+	ReportCheckpointEquivocation abi.MethodNum
+	Postbox                      abi.MethodNum
+	PropagateMsg                 abi.MethodNum
+	InitKill                     abi.MethodNum
+	FinalizeKill                 abi.MethodNum
+	ResolveContent               abi.MethodNum
+	PublishContent               abi.MethodNum
+	PruneContent                 abi.MethodNum
+	UpdateOracleSigners          abi.MethodNum
+	SubmitSignedCheckpoint       abi.MethodNum
+	ProposeCheckPeriod           abi.MethodNum
+	VoteCheckPeriod              abi.MethodNum
+}{builtin0.MethodConstructor, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24}
 
 type SubnetIDParam struct {
 	ID string
@@ -57,7 +73,22 @@ func (a SubnetCoordActor) Exports() []interface{} {
 		6:                         a.CommitChildCheckpoint,
 		7:                         a.Fund,
 		8:                         a.Release,
-		// -1:                         a.XSubnetTx,
+		9:                         a.InitAtomicExec,
+		10:                        a.SubmitAtomicExec,
+		11:                        a.AbortAtomicExec,
+		12:                        a.UpdateSigners,
+		13:                        a.ReportCheckpointEquivocation,
+		14:                        a.Postbox,
+		15:                        a.PropagateMsg,
+		16:                        a.InitKill,
+		17:                        a.FinalizeKill,
+		18:                        a.ResolveContent,
+		19:                        a.PublishContent,
+		20:                        a.PruneContent,
+		21:                        a.UpdateOracleSigners,
+		22:                        a.SubmitSignedCheckpoint,
+		23:                        a.ProposeCheckPeriod,
+		24:                        a.VoteCheckPeriod,
 	}
 }
 
@@ -245,6 +276,15 @@ func (a SubnetCoordActor) CommitChildCheckpoint(rt runtime.Runtime, params *Chec
 		// Get the checkpoint for the current window.
 		ch := st.currWindowCheckpoint(rt)
 
+		// Verify the checkpoint's signer set independently of the subnet
+		// actor's own aggregation, so the parent doesn't have to trust an
+		// honest subnet actor for child finality. A no-op while the subnet
+		// hasn't onboarded a signer set via UpdateSigners yet.
+		checkCid, err := commit.Cid()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "error computing checkpoint's Cid")
+		err = sh.VerifyCheckpointSigners(CheckpointSigVerifier, checkCid, commit)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "checkpoint signer verification failed")
+
 		// Verify that the submitted checkpoint has higher epoch and is
 		// consistent with previous checkpoint before committing.
 		prevCom := sh.PrevCheckpoint
@@ -290,30 +330,67 @@ func (a SubnetCoordActor) CommitChildCheckpoint(rt runtime.Runtime, params *Chec
 	return nil
 }
 
+// TODO: A participant nested deeper than a direct child of this SCA has no
+// way to reach SubmitAtomicExec directly, so its prepare/abort outcome
+// needs to piggyback on a CrossMsgMeta forwarded up through this function
+// like any other cross message, routed to recordAtomicExecStatus instead
+// of storeDownTopMsgMeta/aggChildMsgMeta. That routing depends on a way to
+// tag a CrossMsgMeta as carrying an atomic-exec outcome, which doesn't
+// exist yet; direct-child participants (the common case while subnets are
+// shallow) already work end-to-end via SubmitAtomicExec.
+//
+// Note: a mm carrying a PayloadCid needs no special handling here. It
+// travels verbatim through whichever of the branches below picks it up
+// (storeDownTopMsgMeta, aux, addTopDownCrossMsg, or the Postbox), so the
+// resolver entry PublishContent registered for it is reachable from
+// wherever mm ends up via ResolveContent, without this function needing to
+// know PayloadCid exists.
 func (st *SCAState) applyCheckMsgs(rt runtime.Runtime, windowCh *schema.Checkpoint, childCh *schema.Checkpoint) {
 
-	// aux map[to]CrossMsgMeta
+	// aux map[to]CrossMsgMeta, for messages this SCA can forward up as-is.
 	aux := make(map[string][]schema.CrossMsgMeta)
 	for _, mm := range childCh.CrossMsgs() {
-		// if it is directed to this subnet, add it to down-top messages
-		// for the consensus algorithm in the subnet to pick it up.
-		if mm.To == st.NetworkName.String() {
-			// Add to DownTopMsgMeta
+		switch {
+		case mm.To == st.NetworkName.String():
+			// Directed to this subnet: add it to down-top messages for the
+			// consensus algorithm in the subnet to pick it up.
 			st.storeDownTopMsgMeta(rt, mm)
-		} else {
-			// If not add to the aux structure to update the checkpoint when we've
-			// gone through all crossMsgs
+		case isAncestor(st.NetworkName.String(), mm.To):
+			// Genuinely headed further up the hierarchy: the existing
+			// up-going path (aggregating into our own outgoing checkpoint)
+			// already gets it where it needs to go.
 			_, ok := aux[mm.To]
 			if !ok {
 				aux[mm.To] = []schema.CrossMsgMeta{mm}
 			} else {
 				aux[mm.To] = append(aux[mm.To], mm)
 			}
+		default:
+			if child, ok := childHop(st.NetworkName.String(), mm.To); ok {
+				// Headed down into one of our own subtrees: route it
+				// straight to that child's top-down queue instead of
+				// aggregating it into our own checkpoint, which only ever
+				// goes up.
+				childSh, has, err := st.GetSubnet(adt.AsStore(rt), address.SubnetID(child))
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "error fetching child subnet state")
+				if has {
+					childSh.addTopDownCrossMsg(rt, st, mm)
+					continue
+				}
+			}
+			// Neither resolvable via the existing top-down nor up-going
+			// path (e.g. the destination is a sibling subtree, or the
+			// child subnet it needs doesn't exist): park it in the
+			// Postbox for an owner to propagate another hop once it can
+			// be resolved. The source subnet's actor is the initial owner.
+			sourceAddr, err := hierarchical.SubnetID(childCh.Data.Source).Actor()
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "error getting checkpoint source")
+			st.storePostboxMsg(rt, mm, sourceAddr.String())
 		}
 	}
 
-	// Aggregate all the msgsMeta directed to other subnets in the hierarchy
-	// into the checkpoint
+	// Aggregate all the msgsMeta directed further up the hierarchy into the
+	// checkpoint.
 	st.aggChildMsgMeta(rt, windowCh, aux)
 }
 
@@ -394,6 +471,9 @@ func (a SubnetCoordActor) Fund(rt runtime.Runtime, params *SubnetIDParam) *abi.E
 		if !has {
 			rt.Abortf(exitcode.ErrIllegalArgument, "subnet for for actor hasn't been registered yet")
 		}
+		if sh.Status == Terminating {
+			rt.Abortf(exitcode.ErrForbidden, "can't fund a subnet that's being killed")
+		}
 
 		// Freeze funds
 		sh.freezeFunds(rt, rt.Caller(), value)
@@ -434,3 +514,165 @@ func (a SubnetCoordActor) Release(rt runtime.Runtime, _ *abi.EmptyValue) *abi.Em
 	})
 	return nil
 }
+
+// InitAtomicExecParams is the cross-subnet execution proposal posted to
+// the SCA of the common ancestor subnet where coordination happens.
+type InitAtomicExecParams struct {
+	// Participants are the subnets (as hierarchical.SubnetID strings) that
+	// must all prepare for the execution to commit.
+	Participants []string
+	Locks        []schema.CrossMsgMeta
+	Deadline     abi.ChainEpoch
+}
+
+// InitAtomicExec registers a new atomic execution proposal and takes a gas
+// deposit from the caller to cover the eventual commit/abort round-trip;
+// it's refunded in full once the execution settles.
+//
+// This SCA is implicitly the common ancestor: InitAtomicExec is only ever
+// called at the subnet where every participant's path up the hierarchy
+// converges, same as CommitChildCheckpoint is only ever called by a direct
+// child.
+func (a SubnetCoordActor) InitAtomicExec(rt runtime.Runtime, params *InitAtomicExecParams) *AtomicExecIDParam {
+	rt.ValidateImmediateCallerType(actor.SubnetActorCodeID, builtin.AccountActorCodeID)
+
+	value := rt.ValueReceived()
+	if value.LessThanEqual(big.NewInt(0)) {
+		rt.Abortf(exitcode.ErrIllegalArgument, "atomic execution requires a gas deposit")
+	}
+
+	participants := make([]address.SubnetID, len(params.Participants))
+	for i, p := range params.Participants {
+		participants[i] = address.SubnetID(p)
+	}
+
+	var execCid cid.Cid
+	var st SCAState
+	rt.StateTransaction(&st, func() {
+		execCid = st.initAtomicExec(rt, rt.Caller(), value, AtomicExecParams{
+			Participants: participants,
+			Locks:        params.Locks,
+			Deadline:     params.Deadline,
+		})
+	})
+
+	return &AtomicExecIDParam{Cid: execCid.String()}
+}
+
+// SubmitAtomicExecParams carries a participant's outcome for a previously
+// registered atomic execution.
+type SubmitAtomicExecParams struct {
+	Cid     string
+	Aborted bool
+}
+
+// SubmitAtomicExec records the prepare (or abort) outcome of the
+// participant subnet calling it. Once every participant has prepared, the
+// execution commits and the initiator's gas deposit is refunded; if any
+// participant aborts, the whole execution aborts and the deposit is
+// refunded immediately rather than waiting out the deadline.
+//
+// A participant nested deeper in the hierarchy than a direct child of this
+// SCA can't call this directly, and applyCheckMsgs does not route such an
+// outcome here either, so it is not recorded.
+func (a SubnetCoordActor) SubmitAtomicExec(rt runtime.Runtime, params *SubmitAtomicExecParams) *abi.EmptyValue {
+	rt.ValidateImmediateCallerType(actor.SubnetActorCodeID)
+	subnetActorAddr := rt.Caller()
+
+	execCid, err := cid.Decode(params.Cid)
+	if err != nil {
+		rt.Abortf(exitcode.ErrIllegalArgument, "invalid atomic exec Cid: %s", err)
+	}
+
+	var st SCAState
+	var refundTo address.Address
+	var refund abi.TokenAmount
+	rt.StateTransaction(&st, func() {
+		participant := address.NewSubnetID(st.NetworkName, subnetActorAddr)
+		status := ExecPrepared
+		if params.Aborted {
+			status = ExecAborted
+		}
+		refundTo, refund = st.recordAtomicExecStatus(rt, execCid, participant, status)
+	})
+
+	if !refund.IsZero() {
+		code := rt.Send(refundTo, builtin.MethodSend, nil, refund, &builtin.Discard{})
+		if !code.IsSuccess() {
+			rt.Abortf(exitcode.ErrIllegalState, "failed refunding atomic exec gas lock to initiator")
+		}
+	}
+	return nil
+}
+
+// AbortAtomicExec finalizes the abort of an atomic execution whose
+// deadline (plus AtomicExecChallengePeriod) has already passed without
+// every participant preparing, refunding the initiator's gas deposit.
+func (a SubnetCoordActor) AbortAtomicExec(rt runtime.Runtime, params *AtomicExecIDParam) *abi.EmptyValue {
+	rt.ValidateImmediateCallerAcceptAny()
+
+	execCid, err := cid.Decode(params.Cid)
+	if err != nil {
+		rt.Abortf(exitcode.ErrIllegalArgument, "invalid atomic exec Cid: %s", err)
+	}
+
+	var st SCAState
+	var refundTo address.Address
+	var refund abi.TokenAmount
+	rt.StateTransaction(&st, func() {
+		refundTo, refund = st.abortAtomicExec(rt, execCid)
+	})
+
+	if !refund.IsZero() {
+		code := rt.Send(refundTo, builtin.MethodSend, nil, refund, &builtin.Discard{})
+		if !code.IsSuccess() {
+			rt.Abortf(exitcode.ErrIllegalState, "failed refunding atomic exec gas lock to initiator")
+		}
+	}
+	return nil
+}
+
+// SignerParams names one member of a signer set update, mirroring
+// SubnetSigner over the wire.
+type SignerParams struct {
+	Addr   string
+	Weight abi.TokenAmount
+}
+
+// UpdateSignersParams replaces a subnet's authoritative checkpoint signer
+// set and the weight threshold CommitChildCheckpoint requires of it.
+type UpdateSignersParams struct {
+	Signers   []SignerParams
+	Threshold abi.TokenAmount
+}
+
+// UpdateSigners lets a subnet actor onboard or rotate the signer set that
+// CommitChildCheckpoint independently verifies its checkpoints' aggregate
+// signatures against. Only the subnet actor itself may call this, same as
+// AddStake.
+func (a SubnetCoordActor) UpdateSigners(rt runtime.Runtime, params *UpdateSignersParams) *abi.EmptyValue {
+	rt.ValidateImmediateCallerType(actor.SubnetActorCodeID)
+	SubnetActorAddr := rt.Caller()
+
+	signers := make([]SubnetSigner, len(params.Signers))
+	for i, s := range params.Signers {
+		addr, err := address.NewFromString(s.Addr)
+		if err != nil {
+			rt.Abortf(exitcode.ErrIllegalArgument, "invalid signer address %s: %s", s.Addr, err)
+		}
+		signers[i] = SubnetSigner{Addr: addr, Weight: s.Weight}
+	}
+
+	var st SCAState
+	rt.StateTransaction(&st, func() {
+		sh, has, err := st.getSubnetFromActorAddr(adt.AsStore(rt), SubnetActorAddr)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "error fetching subnet state")
+		if !has {
+			rt.Abortf(exitcode.ErrIllegalArgument, "subnet for actor hasn't been registered yet")
+		}
+
+		sh.UpdateSigners(rt, signers, params.Threshold)
+		st.flushSubnet(rt, sh)
+	})
+	return nil
+}
@@ -33,6 +33,37 @@ const (
 	// Bear in mind that we cast to Int64 when marshalling in
 	// some places
 	MaxNonce = ^uint64(0)
+
+	// AtomicExecChallengePeriod extends an execution's Deadline: once the
+	// deadline passes, AbortAtomicExec doesn't finalize the abort
+	// immediately. It only takes effect after this many additional epochs,
+	// giving a participant whose prepare was delayed in transit a window to
+	// still land it and pull the execution back to Committed instead.
+	AtomicExecChallengePeriod = abi.ChainEpoch(10)
+
+	// EquivocationSlashPercent is the percentage of a subnet's locked Stake
+	// burned/bountied out by ReportCheckpointEquivocation for a single
+	// reported equivocation.
+	// TODO: Same caveat as MinSubnetStake: needs real economic analysis
+	// before this is more than a placeholder.
+	EquivocationSlashPercent = 10
+
+	// EquivocationBountyPercent is the share of the slashed amount paid to
+	// whoever calls ReportCheckpointEquivocation, out of
+	// EquivocationSlashPercent; the rest is burned.
+	EquivocationBountyPercent = 50
+
+	// KillDrainPeriod is how long, in epochs, InitKill gives Release calls
+	// to drain a subnet's in-flight funds before FinalizeKill is allowed to
+	// run.
+	// TODO: Same caveat as MinSubnetStake: needs real analysis of expected
+	// drain throughput before this is more than a placeholder.
+	KillDrainPeriod = abi.ChainEpoch(100)
+
+	// RefundChunkSize caps how many pending fund injections FinalizeKill
+	// refunds in a single call, so a subnet with many outstanding funders
+	// doesn't blow the message-execution gas limit draining in one go.
+	RefundChunkSize = 50
 )
 
 var (
@@ -47,9 +78,11 @@ var (
 type Status uint64
 
 const (
-	Active   Status = iota // Active and operating. Has permission to interact with other chains in the hierarchy
-	Inactive               // Waiting for the stake to be top-up over the MinStake threshold
-	Killed                 // Not active anymore.
+	Active      Status = iota // Active and operating. Has permission to interact with other chains in the hierarchy
+	Inactive                  // Waiting for the stake to be top-up over the MinStake threshold
+	Killed                    // Not active anymore.
+	Slashed                   // Caught in a detectable misbehavior and slashed below MinStake. Can't commit checkpoints until re-staked.
+	Terminating               // InitKill has been called: draining in-flight funds before FinalizeKill removes the subnet.
 
 )
 
@@ -82,6 +115,59 @@ type SCAState struct {
 	// Keep track of the next nonce of the message to be applied.
 	AppliedBottomUpNonce uint64
 	AppliedTopDownNonce  uint64
+
+	// AtomicExecRegistry tracks cross-subnet atomic executions coordinated
+	// by this SCA (i.e. this subnet is the common ancestor named in the
+	// proposal). Keyed by the Cid of the AtomicExec entry as first stored
+	// by initAtomicExec.
+	AtomicExecRegistry cid.Cid // HAMT[cid]AtomicExec
+	// AtomicExecNonce is the next nonce assigned to a proposal initiated at
+	// this SCA, so two otherwise-identical proposals don't collide on Cid.
+	AtomicExecNonce uint64
+
+	// ReportedEquivocations is the set of equivocation evidence already
+	// slashed, keyed by the Cid of an EquivocationEvidence{A, B}. Prevents
+	// the same pair of conflicting checkpoints from being reported (and
+	// bountied) more than once.
+	ReportedEquivocations cid.Cid // HAMT[cid]EquivocationEvidence
+
+	// Postbox holds cross-messages applyCheckMsgs couldn't resolve to
+	// either the existing top-down path (a registered child subtree) or
+	// the existing up-going path (aggregating into this SCA's own
+	// outgoing checkpoint), keyed by the Cid of their schema.CrossMsgMeta.
+	// PropagateMsg lets an owner pick one back up and move it another hop.
+	Postbox cid.Cid // HAMT[cid]PostboxItem
+
+	// ContentIndex resolves the payload Cid of a large off-chain value
+	// referenced by a CrossMsgMeta's PayloadCid to the subnet actually
+	// holding it, so a receiver can fetch the bytes off-band instead of
+	// trusting whichever peer relayed the checkpoint. Populated by
+	// PublishContent, consulted by ResolveContent, and pruned by
+	// PruneContent once an entry's AvailUntil has passed.
+	ContentIndex cid.Cid // HAMT[cid]ContentIndexEntry
+
+	// CheckpointOracle is the network-wide committee SubmitSignedCheckpoint
+	// checks a checkpoint's aggregate signature against, as an alternative
+	// to relying solely on miner consensus to authenticate this SCA's own
+	// checkpoints.
+	CheckpointOracle CheckpointOracle
+
+	// PeriodHistory is the history of CheckPeriod values this SCA has used,
+	// consulted by CurrWindowCheckpoint/RawCheckpoint so windows that
+	// predate a ProposeCheckPeriod/VoteCheckPeriod switchover (or an
+	// adaptive adjustment) still bucket to the period they were actually
+	// computed under. See period.go.
+	PeriodHistory cid.Cid // AMT[PeriodHistoryEntry]
+
+	// CheckPeriodProposals holds in-flight governance votes to change
+	// CheckPeriod, keyed by their own Cid.
+	CheckPeriodProposals cid.Cid // HAMT[cid]CheckPeriodProposal
+
+	// AdaptivePeriod, when set, has flushCheckpoint automatically grow or
+	// shrink CheckPeriod based on observed per-window checkpoint
+	// throughput, instead of requiring a ProposeCheckPeriod/
+	// VoteCheckPeriod governance round for every adjustment.
+	AdaptivePeriod bool
 }
 
 func ConstructSCAState(store adt.Store, params *ConstructorParams) (*SCAState, error) {
@@ -101,6 +187,26 @@ func ConstructSCAState(store adt.Store, params *ConstructorParams) (*SCAState, e
 	if err != nil {
 		return nil, xerrors.Errorf("failed to create empty AMT: %w", err)
 	}
+	emptyAtomicExecRegistryCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty map: %w", err)
+	}
+	emptyReportedEquivocationsCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty map: %w", err)
+	}
+	emptyPostboxCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty map: %w", err)
+	}
+	emptyContentIndexCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty map: %w", err)
+	}
+	emptyCheckPeriodProposalsCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty map: %w", err)
+	}
 
 	nn := address.SubnetID(params.NetworkName)
 	// Don't allow really small checkpoint periods for now.
@@ -109,16 +215,43 @@ func ConstructSCAState(store adt.Store, params *ConstructorParams) (*SCAState, e
 		period = DefaultCheckpointPeriod
 	}
 
+	emptyPeriodHistoryAMT, err := adt.StoreEmptyArray(store, PeriodHistoryAMTBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty AMT: %w", err)
+	}
+	periodHistoryArr, err := adt.AsArray(store, emptyPeriodHistoryAMT, PeriodHistoryAMTBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load period history: %w", err)
+	}
+	if err := periodHistoryArr.Set(0, &PeriodHistoryEntry{FromEpoch: 0, Period: period}); err != nil {
+		return nil, xerrors.Errorf("failed to seed period history: %w", err)
+	}
+	periodHistoryRoot, err := periodHistoryArr.Root()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to flush period history: %w", err)
+	}
+
 	return &SCAState{
-		NetworkName:          nn,
-		TotalSubnets:         0,
-		MinStake:             MinSubnetStake,
-		Subnets:              emptySubnetsMapCid,
-		CheckPeriod:          period,
-		Checkpoints:          emptyCheckpointsMapCid,
-		CheckMsgsRegistry:    emptyMsgsMetaMapCid,
-		BottomUpMsgsMeta:     emptyBottomUpMsgsAMT,
-		AppliedBottomUpNonce: MaxNonce, // We need inital nonce+1 to be 0 due to how msgs are applied.
+		NetworkName:           nn,
+		TotalSubnets:          0,
+		MinStake:              MinSubnetStake,
+		Subnets:               emptySubnetsMapCid,
+		CheckPeriod:           period,
+		Checkpoints:           emptyCheckpointsMapCid,
+		CheckMsgsRegistry:     emptyMsgsMetaMapCid,
+		BottomUpMsgsMeta:      emptyBottomUpMsgsAMT,
+		AppliedBottomUpNonce:  MaxNonce, // We need inital nonce+1 to be 0 due to how msgs are applied.
+		AtomicExecRegistry:    emptyAtomicExecRegistryCid,
+		ReportedEquivocations: emptyReportedEquivocationsCid,
+		Postbox:               emptyPostboxCid,
+		ContentIndex:          emptyContentIndexCid,
+		// Threshold starts at zero, same as a fresh Subnet's
+		// SignerThreshold: no committee has been onboarded via
+		// UpdateOracleSigners yet, so SubmitSignedCheckpoint has nothing to
+		// verify against until it is.
+		CheckpointOracle:     CheckpointOracle{Threshold: big.Zero()},
+		PeriodHistory:        periodHistoryRoot,
+		CheckPeriodProposals: emptyCheckPeriodProposalsCid,
 	}, nil
 }
 
@@ -162,7 +295,11 @@ func (st *SCAState) flushSubnet(rt runtime.Runtime, sh *Subnet) {
 // child checkpoint, until the windows passes that the template is frozen
 // and is ready for miners to populate the rest and sign it.
 func (st *SCAState) CurrWindowCheckpoint(store adt.Store, epoch abi.ChainEpoch) (*schema.Checkpoint, error) {
-	chEpoch := types.WindowEpoch(epoch, st.CheckPeriod)
+	period, err := periodAt(store, st.PeriodHistory, epoch)
+	if err != nil {
+		return nil, err
+	}
+	chEpoch := types.WindowEpoch(epoch, period)
 	ch, found, err := st.GetCheckpoint(store, chEpoch)
 	if err != nil {
 		return nil, err
@@ -190,7 +327,11 @@ func RawCheckpoint(st *SCAState, store adt.Store, epoch abi.ChainEpoch) (*schema
 	if epoch < 0 {
 		return nil, xerrors.Errorf("epoch can't be negative")
 	}
-	chEpoch := types.CheckpointEpoch(epoch, st.CheckPeriod)
+	period, err := periodAt(store, st.PeriodHistory, epoch)
+	if err != nil {
+		return nil, err
+	}
+	chEpoch := types.CheckpointEpoch(epoch, period)
 	ch, found, err := st.GetCheckpoint(store, chEpoch)
 	if err != nil {
 		return nil, err
@@ -232,6 +373,8 @@ func (st *SCAState) flushCheckpoint(rt runtime.Runtime, ch *schema.Checkpoint) {
 	// Flush checkpoints
 	st.Checkpoints, err = checks.Root()
 	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush checkpoints")
+
+	st.adjustAdaptivePeriod(rt, ch)
 }
 
 // Get subnet from its subnet actor address.
@@ -243,18 +386,26 @@ func (st *SCAState) getSubnetFromActorAddr(s adt.Store, addr address.Address) (*
 func (st *SCAState) registerSubnet(rt runtime.Runtime, shid address.SubnetID, stake big.Int) {
 	emptyTopDownMsgsAMT, err := adt.StoreEmptyArray(adt.AsStore(rt), CrossMsgsAMTBitwidth)
 	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to create empty top-down msgs array")
+	emptyTopDownCrossMsgsAMT, err := adt.StoreEmptyArray(adt.AsStore(rt), CrossMsgsAMTBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to create empty top-down cross msgs array")
 
 	// We always initialize in instantiated state
 	status := Active
 
 	sh := &Subnet{
-		ID:             shid,
-		ParentID:       st.NetworkName,
-		Stake:          stake,
-		TopDownMsgs:    emptyTopDownMsgsAMT,
-		CircSupply:     big.Zero(),
-		Status:         status,
-		PrevCheckpoint: *schema.EmptyCheckpoint,
+		ID:                shid,
+		ParentID:          st.NetworkName,
+		Stake:             stake,
+		TopDownMsgs:       emptyTopDownMsgsAMT,
+		TopDownCrossMsgs:  emptyTopDownCrossMsgsAMT,
+		CircSupply:        big.Zero(),
+		Status:            status,
+		PrevCheckpoint:    *schema.EmptyCheckpoint,
+		// SignerSet starts empty and SignerThreshold at zero: until the
+		// subnet actor calls UpdateSigners, CommitChildCheckpoint skips
+		// signer verification rather than rejecting every checkpoint from a
+		// subnet that hasn't onboarded a signer set yet.
+		SignerThreshold: big.Zero(),
 	}
 
 	// Increase the number of child subnets for the current network.
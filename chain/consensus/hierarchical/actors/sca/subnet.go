@@ -0,0 +1,243 @@
+package sca
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/filecoin-project/specs-actors/v7/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v7/actors/runtime"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// SubnetSigner is one member of a Subnet's authoritative signer set: a
+// miner address and the weight its vote carries toward SignerThreshold.
+type SubnetSigner struct {
+	Addr   address.Address
+	Weight big.Int
+}
+
+// Subnet is the SCA-side record of a registered child subnet.
+type Subnet struct {
+	ID       address.SubnetID
+	ParentID address.SubnetID
+	Stake    big.Int
+	// TopDownMsgs is the AMT of cross-messages sent down to this subnet,
+	// indexed by nonce.
+	TopDownMsgs cid.Cid // AMT[FundMsg]
+	CircSupply  big.Int
+	Status      Status
+
+	// TopDownCrossMsgs is the AMT of general cross-subnet messages (as
+	// opposed to FundMsg's fixed fund-injection shape) routed down to this
+	// subnet by PropagateMsg, indexed by TopDownCrossMsgsNonce.
+	TopDownCrossMsgs      cid.Cid // AMT[schema.CrossMsgMeta]
+	TopDownCrossMsgsNonce uint64
+	// PrevCheckpoint is the last checkpoint committed for this subnet.
+	PrevCheckpoint schema.Checkpoint
+
+	// SignerSet is the authoritative set of miners allowed to contribute to
+	// a checkpoint's aggregate signature, along with their individual
+	// weights. It starts empty at Register (the subnet doesn't have miners
+	// yet) and is populated and kept current via UpdateSigners as miners
+	// join and leave.
+	SignerSet []SubnetSigner
+	// SignerThreshold is the minimum sum of SubnetSigner.Weight that must
+	// back a checkpoint's aggregate signature for CommitChildCheckpoint to
+	// accept it. A zero threshold (the state before the first
+	// UpdateSigners call) means signature verification is skipped, so
+	// subnets don't need to onboard a signer set before their first
+	// checkpoint.
+	SignerThreshold big.Int
+
+	// FundMsgCount is the number of FundMsg entries ever appended to
+	// TopDownMsgs for this subnet, so InitKill knows how many pending
+	// injections it needs FinalizeKill to eventually refund.
+	FundMsgCount uint64
+
+	// UnlockEpoch is the epoch at or after which FinalizeKill is allowed to
+	// run, set by InitKill to give outstanding funds a window to drain via
+	// ordinary Release calls first. Meaningless unless Status is
+	// Terminating.
+	UnlockEpoch abi.ChainEpoch
+	// RefundQueue is the snapshot of TopDownMsgs taken by InitKill: the
+	// fixed list of pending fund injections FinalizeKill works through,
+	// chunked RefundChunkSize entries at a time starting at RefundCursor,
+	// to refund each original funder once the subnet is gone for good.
+	RefundQueue  cid.Cid // AMT[FundMsg]
+	RefundTotal  uint64
+	RefundCursor uint64
+}
+
+// FundMsg is a minimal top-down cross-message: an injection of funds from
+// the parent into the subnet, indexed in TopDownMsgs by Nonce.
+type FundMsg struct {
+	Nonce uint64
+	To    address.Address
+	Value big.Int
+}
+
+// addStake adjusts sh's locked stake by delta (negative to release) and
+// persists the subnet. A Slashed subnet whose stake is brought back up to
+// st.MinStake becomes Active again.
+func (sh *Subnet) addStake(rt runtime.Runtime, st *SCAState, delta big.Int) {
+	sh.Stake = big.Add(sh.Stake, delta)
+	if sh.Stake.LessThan(big.Zero()) {
+		rt.Abortf(exitcode.ErrIllegalState, "stake adjustment would leave subnet with negative stake")
+	}
+	if sh.Status == Slashed && sh.Stake.GreaterThanEqual(st.MinStake) {
+		sh.Status = Active
+	}
+	st.flushSubnet(rt, sh)
+}
+
+// freezeFunds records value as newly circulating in the subnet. The actual
+// FIL was already received by this actor; from is kept only for the
+// caller's own bookkeeping/events, not for any check here.
+func (sh *Subnet) freezeFunds(rt runtime.Runtime, from address.Address, value big.Int) {
+	sh.CircSupply = big.Add(sh.CircSupply, value)
+}
+
+// addFundMsg appends a FundMsg for value to sh's TopDownMsgs AMT under the
+// SCA's next nonce.
+func (sh *Subnet) addFundMsg(rt runtime.Runtime, value big.Int) {
+	arr, err := adt.AsArray(adt.AsStore(rt), sh.TopDownMsgs, CrossMsgsAMTBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load top-down msgs")
+
+	var st SCAState
+	rt.StateReadonly(&st)
+	nonce := st.Nonce
+
+	msg := &FundMsg{Nonce: nonce, To: rt.Caller(), Value: value}
+	err = arr.Set(nonce, msg)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to append fund msg")
+	sh.FundMsgCount++
+
+	sh.TopDownMsgs, err = arr.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush top-down msgs")
+}
+
+// addTopDownCrossMsg appends mm to sh's TopDownCrossMsgs AMT under the
+// subnet's next TopDownCrossMsgsNonce and persists the subnet.
+func (sh *Subnet) addTopDownCrossMsg(rt runtime.Runtime, st *SCAState, mm schema.CrossMsgMeta) {
+	arr, err := adt.AsArray(adt.AsStore(rt), sh.TopDownCrossMsgs, CrossMsgsAMTBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load top-down cross msgs")
+
+	err = arr.Set(sh.TopDownCrossMsgsNonce, &mm)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to enqueue top-down cross msg")
+	sh.TopDownCrossMsgsNonce++
+
+	sh.TopDownCrossMsgs, err = arr.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush top-down cross msgs")
+	st.flushSubnet(rt, sh)
+}
+
+// UpdateSigners replaces sh's authoritative signer set and threshold. It's
+// the only way SignerSet/SignerThreshold change outside of the zero-value
+// they're seeded with at Register.
+func (sh *Subnet) UpdateSigners(rt runtime.Runtime, signers []SubnetSigner, threshold big.Int) {
+	if threshold.LessThan(big.Zero()) {
+		rt.Abortf(exitcode.ErrIllegalArgument, "signer threshold can't be negative")
+	}
+	for _, s := range signers {
+		if s.Addr.Protocol() != address.BLS {
+			rt.Abortf(exitcode.ErrIllegalArgument, "signer %s is not a BLS address", s.Addr)
+		}
+		if s.Weight.LessThan(big.Zero()) {
+			rt.Abortf(exitcode.ErrIllegalArgument, "signer %s has a negative weight", s.Addr)
+		}
+	}
+	sh.SignerSet = signers
+	sh.SignerThreshold = threshold
+}
+
+// BLSAggregateVerifier checks a BLS aggregate signature. Mirrors the
+// interface the subnet actor uses for the same purpose: actor code can't
+// reach into filecoin-ffi directly, so production wiring supplies a real
+// implementation and tests supply a fake.
+type BLSAggregateVerifier interface {
+	VerifyAggregate(sig []byte, digest []byte, pubKeys [][]byte) bool
+}
+
+// CheckpointSigVerifier is the BLS aggregate verifier CommitChildCheckpoint
+// checks a committed checkpoint's Signature/Signers against. It's a package
+// var rather than a call to rt.VerifySignature because that syscall only
+// checks one signer against one plaintext; it can't validate a real
+// aggregate signature across the many pubkeys a subnet's SignerSet may
+// name. Node startup is expected to set this to an implementation backed by
+// filecoin-ffi before any subnet enables signer verification via
+// UpdateSigners.
+var CheckpointSigVerifier BLSAggregateVerifier
+
+// VerifyCheckpointSigners checks that commit.Signature is a valid BLS
+// aggregate signature over checkCid, contributed by a subset of sh's
+// SignerSet whose combined weight meets SignerThreshold. If SignerThreshold
+// is zero (no signer set has been onboarded via UpdateSigners yet), it's a
+// no-op: the parent has nothing to independently verify against.
+func (sh *Subnet) VerifyCheckpointSigners(v BLSAggregateVerifier, checkCid cid.Cid, commit *schema.Checkpoint) error {
+	if sh.SignerThreshold.LessThanEqual(big.Zero()) {
+		return nil
+	}
+	return verifyAggregateCheckpointSig(v, checkCid, commit, sh.SignerSet, sh.SignerThreshold)
+}
+
+// VerifyCheckpointSignersStrict is VerifyCheckpointSigners without the
+// zero-threshold no-op. Callers punishing a subnet for an equivocation (as
+// opposed to just accepting its commits) need real cryptographic proof that
+// the named signer actually signed both conflicting checkpoints regardless
+// of whether the subnet has onboarded a SignerSet weight threshold yet.
+func (sh *Subnet) VerifyCheckpointSignersStrict(v BLSAggregateVerifier, checkCid cid.Cid, commit *schema.Checkpoint) error {
+	return verifyAggregateCheckpointSig(v, checkCid, commit, sh.SignerSet, sh.SignerThreshold)
+}
+
+// verifyAggregateCheckpointSig checks that commit.Signature is a valid BLS
+// aggregate signature over checkCid, contributed by a subset of signers
+// whose combined weight meets threshold. Shared by Subnet.VerifyCheckpointSigners
+// (a child subnet's own signer set) and CheckpointOracle's signer
+// verification (the network-wide committee attesting to this SCA's own
+// checkpoints), which otherwise check the identical shape of evidence
+// against two differently-scoped signer sets.
+func verifyAggregateCheckpointSig(v BLSAggregateVerifier, checkCid cid.Cid, commit *schema.Checkpoint, signerSet []SubnetSigner, threshold big.Int) error {
+	if len(commit.Signature) == 0 || len(commit.Signers) == 0 {
+		return xerrors.Errorf("checkpoint has no aggregate signature to verify")
+	}
+
+	signers, err := bitfield.NewFromBytes(commit.Signers)
+	if err != nil {
+		return xerrors.Errorf("decoding signers bitfield: %w", err)
+	}
+	idxs, err := signers.All(uint64(len(signerSet)))
+	if err != nil {
+		return xerrors.Errorf("expanding signers bitfield: %w", err)
+	}
+	if len(idxs) == 0 {
+		return xerrors.Errorf("checkpoint signers bitfield is empty")
+	}
+
+	weight := big.Zero()
+	pubKeys := make([][]byte, 0, len(idxs))
+	for _, idx := range idxs {
+		if idx >= uint64(len(signerSet)) {
+			return xerrors.Errorf("signer index %d out of range for %d signers", idx, len(signerSet))
+		}
+		signer := signerSet[idx]
+		if signer.Addr.Protocol() != address.BLS {
+			return xerrors.Errorf("signer %s is not a BLS address, can't verify aggregate", signer.Addr)
+		}
+		weight = big.Add(weight, signer.Weight)
+		pubKeys = append(pubKeys, signer.Addr.Payload())
+	}
+
+	if weight.LessThan(threshold) {
+		return xerrors.Errorf("checkpoint signers only carry %s weight, below threshold %s", weight, threshold)
+	}
+
+	if !v.VerifyAggregate(commit.Signature, checkCid.Bytes(), pubKeys) {
+		return xerrors.Errorf("aggregate signature verification failed for checkpoint %s", checkCid)
+	}
+	return nil
+}
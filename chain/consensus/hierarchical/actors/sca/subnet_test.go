@@ -0,0 +1,103 @@
+package sca
+
+import (
+	"testing"
+
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+)
+
+func cidForTest(t *testing.T) cid.Cid {
+	h, err := mh.Sum([]byte("checkpoint"), mh.SHA2_256, -1)
+	require.NoError(t, err)
+	return cid.NewCidV1(cid.DagCBOR, h)
+}
+
+type fakeBLSVerifier struct {
+	ok bool
+}
+
+func (f fakeBLSVerifier) VerifyAggregate(sig []byte, digest []byte, pubKeys [][]byte) bool {
+	return f.ok
+}
+
+func blsAddr(t *testing.T, payload byte) address.Address {
+	raw := make([]byte, 48)
+	raw[0] = payload
+	a, err := address.NewBLSAddress(raw)
+	require.NoError(t, err)
+	return a
+}
+
+func signersBitfield(t *testing.T, idxs ...uint64) []byte {
+	bf := bitfield.NewFromSet(idxs)
+	b, err := bf.Bytes()
+	require.NoError(t, err)
+	return b
+}
+
+func TestVerifyCheckpointSignersSkippedBelowThreshold(t *testing.T) {
+	sh := &Subnet{SignerThreshold: big.Zero()}
+	commit := &schema.Checkpoint{}
+
+	err := sh.VerifyCheckpointSigners(fakeBLSVerifier{ok: false}, cidForTest(t), commit)
+	require.NoError(t, err)
+}
+
+func TestVerifyCheckpointSignersRejectsUnderThreshold(t *testing.T) {
+	m0, m1 := blsAddr(t, 0), blsAddr(t, 1)
+	sh := &Subnet{
+		SignerSet: []SubnetSigner{
+			{Addr: m0, Weight: big.NewInt(1)},
+			{Addr: m1, Weight: big.NewInt(1)},
+		},
+		SignerThreshold: big.NewInt(2),
+	}
+	commit := &schema.Checkpoint{
+		Signature: []byte("sig"),
+		Signers:   signersBitfield(t, 0),
+	}
+
+	err := sh.VerifyCheckpointSigners(fakeBLSVerifier{ok: true}, cidForTest(t), commit)
+	require.Error(t, err)
+}
+
+func TestVerifyCheckpointSignersHonorsVerifierResult(t *testing.T) {
+	m0, m1 := blsAddr(t, 0), blsAddr(t, 1)
+	sh := &Subnet{
+		SignerSet: []SubnetSigner{
+			{Addr: m0, Weight: big.NewInt(1)},
+			{Addr: m1, Weight: big.NewInt(1)},
+		},
+		SignerThreshold: big.NewInt(2),
+	}
+	commit := &schema.Checkpoint{
+		Signature: []byte("sig"),
+		Signers:   signersBitfield(t, 0, 1),
+	}
+
+	require.NoError(t, sh.VerifyCheckpointSigners(fakeBLSVerifier{ok: true}, cidForTest(t), commit))
+	require.Error(t, sh.VerifyCheckpointSigners(fakeBLSVerifier{ok: false}, cidForTest(t), commit))
+}
+
+func TestVerifyCheckpointSignersRejectsNonBLSSigner(t *testing.T) {
+	idAddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	sh := &Subnet{
+		SignerSet:       []SubnetSigner{{Addr: idAddr, Weight: big.NewInt(1)}},
+		SignerThreshold: big.NewInt(1),
+	}
+	commit := &schema.Checkpoint{
+		Signature: []byte("sig"),
+		Signers:   signersBitfield(t, 0),
+	}
+
+	err = sh.VerifyCheckpointSigners(fakeBLSVerifier{ok: true}, cidForTest(t), commit)
+	require.Error(t, err)
+}
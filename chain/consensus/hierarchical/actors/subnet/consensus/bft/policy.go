@@ -0,0 +1,72 @@
+// Package bft implements consensus.Policy for Tendermint-style BFT
+// subnets: checkpoints require a 2/3 stake-weighted quorum, and the subnet
+// enforces a minimum miner count so it can actually tolerate a Byzantine
+// minority rather than just claiming to.
+package bft
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/consensus"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+)
+
+// MinMiners is the classical BFT safety floor (3f+1 with f=1): below this,
+// the subnet can't tolerate even a single Byzantine or offline miner.
+const MinMiners = 4
+
+// Policy is the bft consensus.Policy.
+type Policy struct {
+	minMinerStake abi.TokenAmount
+}
+
+// New returns a bft Policy requiring minMinerStake to join.
+func New(minMinerStake abi.TokenAmount) *Policy {
+	return &Policy{minMinerStake: minMinerStake}
+}
+
+func (p *Policy) Name() string { return "bft" }
+
+// MinMinerStake returns the policy's configured joining stake.
+func (p *Policy) MinMinerStake() abi.TokenAmount { return p.minMinerStake }
+
+// ValidateJoin enforces the minimum stake; MinMiners is enforced on the
+// read side (ValidateCheckpoint), not on join, since a subnet has to start
+// somewhere below quorum strength.
+func (p *Policy) ValidateJoin(st consensus.JoinState, addr address.Address, stake abi.TokenAmount) error {
+	if stake.LessThan(p.minMinerStake) {
+		return xerrors.Errorf("%w: stake %s below minimum %s", consensus.ErrJoinRejected, stake, p.minMinerStake)
+	}
+	return nil
+}
+
+// ValidateLeave refuses a departure that would drop the subnet below
+// MinMiners.
+func (p *Policy) ValidateLeave(st consensus.JoinState, addr address.Address) error {
+	if !st.IsMiner(addr) {
+		return nil
+	}
+	if st.MinerCount()-1 < MinMiners {
+		return xerrors.Errorf("%w: leaving would drop the subnet below the %d miners BFT requires", consensus.ErrJoinRejected, MinMiners)
+	}
+	return nil
+}
+
+// ValidateCheckpoint refuses to accept votes until the subnet has enough
+// miners to form a meaningful BFT quorum.
+func (p *Policy) ValidateCheckpoint(st consensus.JoinState, ch *schema.Checkpoint) error {
+	if st.MinerCount() < MinMiners {
+		return xerrors.Errorf("checkpoint rejected: subnet has %d miners, fewer than the %d BFT requires", st.MinerCount(), MinMiners)
+	}
+	return nil
+}
+
+// Quorum requires 2/3 of stake, irrespective of the subnet's configured
+// QuorumThreshold: BFT's safety margin isn't something a subnet should be
+// able to weaken.
+func (p *Policy) Quorum() (v0.QuorumPolicy, float32) {
+	return v0.QuorumPowerTwoThirds, v0.SignatureThreshold
+}
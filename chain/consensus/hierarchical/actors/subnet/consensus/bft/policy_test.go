@@ -0,0 +1,57 @@
+package bft
+
+import (
+	"testing"
+
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeJoinState struct {
+	miners []address.Address
+	stake  abi.TokenAmount
+}
+
+func (f fakeJoinState) MinerCount() int { return len(f.miners) }
+
+func (f fakeJoinState) IsMiner(addr address.Address) bool {
+	for _, a := range f.miners {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fakeJoinState) TotalStaked() abi.TokenAmount { return f.stake }
+
+func mustAddr(t *testing.T, s string) address.Address {
+	a, err := address.NewFromString(s)
+	require.NoError(t, err)
+	return a
+}
+
+func TestValidateLeaveRejectsBelowMinMiners(t *testing.T) {
+	miners := make([]address.Address, MinMiners)
+	for i := range miners {
+		miners[i] = mustAddr(t, "t0100"+string(rune('0'+i)))
+	}
+	st := fakeJoinState{miners: miners}
+
+	p := New(abi.NewTokenAmount(1))
+	require.Error(t, p.ValidateLeave(st, miners[0]))
+}
+
+func TestValidateCheckpointRequiresMinMiners(t *testing.T) {
+	p := New(abi.NewTokenAmount(1))
+
+	st := fakeJoinState{miners: []address.Address{mustAddr(t, "t01000")}}
+	require.Error(t, p.ValidateCheckpoint(st, nil))
+
+	full := make([]address.Address, MinMiners)
+	for i := range full {
+		full[i] = mustAddr(t, "t0200"+string(rune('0'+i)))
+	}
+	require.NoError(t, p.ValidateCheckpoint(fakeJoinState{miners: full}, nil))
+}
@@ -0,0 +1,52 @@
+// Package consensus defines the pluggable per-consensus rules a subnet
+// enforces, so SubnetState doesn't grow an if/switch on ConsensusType every
+// time a new consensus flavor is added. Concrete policies live in
+// subpackages (pow, bft, pos); v1.ConstructSubnetState picks one from
+// params.Consensus and takes the subnet's MinMinerStake, Quorum and
+// QuorumThreshold from it. The policy itself is not kept in the state, and
+// no actor method calls its ValidateJoin, ValidateLeave or
+// ValidateCheckpoint hooks.
+package consensus
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+)
+
+// ErrJoinRejected wraps a policy's reason for turning down a join/leave
+// request, as opposed to a plumbing error encountered while evaluating it.
+var ErrJoinRejected = xerrors.New("rejected by consensus policy")
+
+// JoinState is the slice of SubnetState a Policy needs to evaluate a
+// join/leave/checkpoint request. It's kept narrow and version-agnostic so
+// policy packages don't need to import whichever version package
+// SubnetState currently lives in.
+type JoinState interface {
+	MinerCount() int
+	IsMiner(addr address.Address) bool
+	TotalStaked() abi.TokenAmount
+}
+
+// Policy captures the consensus-specific rules a subnet enforces: how much
+// stake joining costs, whether a miner may leave, what a checkpoint vote
+// needs to pass validation, and how votes are tallied for finalization.
+type Policy interface {
+	// Name identifies the policy for logging/debugging.
+	Name() string
+	// MinMinerStake is the minimum stake a miner must post to join.
+	MinMinerStake() abi.TokenAmount
+	// ValidateJoin checks addr's join request against st before it's admitted.
+	ValidateJoin(st JoinState, addr address.Address, stake abi.TokenAmount) error
+	// ValidateLeave checks that addr may leave st right now.
+	ValidateLeave(st JoinState, addr address.Address) error
+	// ValidateCheckpoint runs policy-specific checks on ch before it's
+	// accepted as a vote candidate.
+	ValidateCheckpoint(st JoinState, ch *schema.Checkpoint) error
+	// Quorum is the vote-tallying policy and threshold this consensus
+	// mandates for checkpoint finalization.
+	Quorum() (v0.QuorumPolicy, float32)
+}
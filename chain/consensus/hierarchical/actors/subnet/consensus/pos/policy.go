@@ -0,0 +1,64 @@
+// Package pos implements consensus.Policy for proof-of-stake subnets:
+// voting power follows stake everywhere, so joining, leaving, and
+// checkpoint quorum are all keyed off the stake table rather than miner
+// count.
+package pos
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/consensus"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+)
+
+// Policy is the pos consensus.Policy.
+type Policy struct {
+	minMinerStake abi.TokenAmount
+	threshold     float32
+}
+
+// New returns a pos Policy requiring minMinerStake to join and threshold
+// fraction of stake (by value, not count) to finalize a checkpoint.
+// threshold of 0 defaults to v0.SignatureThreshold.
+func New(minMinerStake abi.TokenAmount, threshold float32) *Policy {
+	if threshold == 0 {
+		threshold = v0.SignatureThreshold
+	}
+	return &Policy{minMinerStake: minMinerStake, threshold: threshold}
+}
+
+func (p *Policy) Name() string { return "pos" }
+
+// MinMinerStake returns the policy's configured joining stake.
+func (p *Policy) MinMinerStake() abi.TokenAmount { return p.minMinerStake }
+
+// ValidateJoin enforces the minimum stake.
+func (p *Policy) ValidateJoin(st consensus.JoinState, addr address.Address, stake abi.TokenAmount) error {
+	if stake.LessThan(p.minMinerStake) {
+		return xerrors.Errorf("%w: stake %s below minimum %s", consensus.ErrJoinRejected, stake, p.minMinerStake)
+	}
+	return nil
+}
+
+// ValidateLeave places no restriction beyond what unstaking already costs
+// the miner in voting power.
+func (p *Policy) ValidateLeave(st consensus.JoinState, addr address.Address) error {
+	return nil
+}
+
+// ValidateCheckpoint refuses votes while the subnet has no staked miners,
+// since a stake-weighted quorum is meaningless at zero total stake.
+func (p *Policy) ValidateCheckpoint(st consensus.JoinState, ch *schema.Checkpoint) error {
+	if st.TotalStaked().IsZero() {
+		return xerrors.Errorf("checkpoint rejected: subnet has no staked miners")
+	}
+	return nil
+}
+
+// Quorum tallies votes by stake, at the policy's configured threshold.
+func (p *Policy) Quorum() (v0.QuorumPolicy, float32) {
+	return v0.QuorumStakeWeighted, p.threshold
+}
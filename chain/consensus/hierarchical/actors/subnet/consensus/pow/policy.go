@@ -0,0 +1,53 @@
+// Package pow implements consensus.Policy for proof-of-work subnets: the
+// original, simplest policy, where joining only costs the configured
+// minimum stake, any miner may leave at any time, and checkpoints finalize
+// by raw miner count at v0.SignatureThreshold.
+package pow
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/consensus"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+)
+
+// Policy is the pow consensus.Policy.
+type Policy struct {
+	minMinerStake abi.TokenAmount
+}
+
+// New returns a pow Policy requiring minMinerStake to join.
+func New(minMinerStake abi.TokenAmount) *Policy {
+	return &Policy{minMinerStake: minMinerStake}
+}
+
+func (p *Policy) Name() string { return "pow" }
+
+// MinMinerStake returns the policy's configured joining stake.
+func (p *Policy) MinMinerStake() abi.TokenAmount { return p.minMinerStake }
+
+// ValidateJoin only enforces the minimum stake.
+func (p *Policy) ValidateJoin(st consensus.JoinState, addr address.Address, stake abi.TokenAmount) error {
+	if stake.LessThan(p.minMinerStake) {
+		return xerrors.Errorf("%w: stake %s below minimum %s", consensus.ErrJoinRejected, stake, p.minMinerStake)
+	}
+	return nil
+}
+
+// ValidateLeave places no restriction on leaving under pow.
+func (p *Policy) ValidateLeave(st consensus.JoinState, addr address.Address) error {
+	return nil
+}
+
+// ValidateCheckpoint has no pow-specific checks beyond the actor's own.
+func (p *Policy) ValidateCheckpoint(st consensus.JoinState, ch *schema.Checkpoint) error {
+	return nil
+}
+
+// Quorum tallies votes by raw miner count, the original SubnetState behavior.
+func (p *Policy) Quorum() (v0.QuorumPolicy, float32) {
+	return v0.QuorumCount, v0.SignatureThreshold
+}
@@ -0,0 +1,50 @@
+package subnet
+
+import (
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/util/adt"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v1"
+)
+
+// MigrateV0toV1 rewrites a v0 subnet state into the v1 layout. It carries
+// every v0 field across unchanged and bootstraps SlashedMiners and
+// EpochChecks as empty HAMTs, the two fields v1 adds. Checkpoints and
+// WindowChecks are left pointing at their existing roots: neither HAMT's
+// internal layout changes between v0 and v1, so there's nothing to rewrite
+// there.
+//
+// Callers are expected to run this once, at the epoch the subnet actor
+// crosses the v1 upgrade, and to persist the actor's new version alongside
+// the migrated head so Load is asked for v1 from then on.
+func MigrateV0toV1(store adt.Store, old *v0.SubnetState) (*v1.SubnetState, error) {
+	emptySlashedMinersCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty slashed miners map: %w", err)
+	}
+	emptyEpochChecksCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty epoch checks map: %w", err)
+	}
+
+	return &v1.SubnetState{
+		Name:            old.Name,
+		ParentID:        old.ParentID,
+		Consensus:       old.Consensus,
+		MinMinerStake:   old.MinMinerStake,
+		Miners:          old.Miners,
+		TotalStake:      old.TotalStake,
+		Stake:           old.Stake,
+		Status:          old.Status,
+		Genesis:         old.Genesis,
+		CheckPeriod:     old.CheckPeriod,
+		Checkpoints:     old.Checkpoints,
+		WindowChecks:    old.WindowChecks,
+		Quorum:          old.Quorum,
+		QuorumThreshold: old.QuorumThreshold,
+		SlashedMiners:   emptySlashedMinersCid,
+		EpochChecks:     emptyEpochChecksCid,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package subnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/big"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/util/adt"
+
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+)
+
+func TestMigrateV0toV1CarriesFieldsAndBootstrapsSlashedMiners(t *testing.T) {
+	bs := blockstore.NewMemorySync()
+	store := adt.WrapStore(context.Background(), cbor.NewCborStore(bs))
+
+	old := &v0.SubnetState{
+		Name:            "testnet",
+		TotalStake:      big.NewInt(100),
+		Quorum:          v0.QuorumStakeWeighted,
+		QuorumThreshold: v0.SignatureThreshold,
+	}
+
+	migrated, err := MigrateV0toV1(store, old)
+	require.NoError(t, err)
+
+	require.Equal(t, old.Name, migrated.Name)
+	require.True(t, old.TotalStake.Equals(migrated.TotalStake))
+	require.Equal(t, old.Quorum, migrated.Quorum)
+	require.Equal(t, old.QuorumThreshold, migrated.QuorumThreshold)
+	require.True(t, migrated.SlashedMiners.Defined())
+
+	m, err := adt.AsMap(store, migrated.SlashedMiners, builtin.DefaultHamtBitwidth)
+	require.NoError(t, err)
+	empty, err := m.Root()
+	require.NoError(t, err)
+	require.Equal(t, migrated.SlashedMiners, empty)
+}
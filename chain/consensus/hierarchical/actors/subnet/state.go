@@ -0,0 +1,78 @@
+// Package subnet is the version-dispatch layer for the subnet actor's
+// on-chain state. The actual field layouts live in versioned subpackages
+// (v0, v1, ...); this package only knows how to pick the right one and how
+// to migrate a subnet's head from one to the next.
+package subnet
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/specs-actors/v6/actors/runtime"
+	"github.com/filecoin-project/specs-actors/v6/actors/util/adt"
+
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v1"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+)
+
+// Version identifies a subnet-actor state layout. It's threaded in from the
+// actor runtime's network version rather than guessed from the serialized
+// bytes, the same way specs-actors versions its built-in actors: a subnet
+// created before an upgrade epoch keeps being loaded with the methods it
+// was created under until something (CommitChildCheckpoint, say) migrates
+// it forward.
+type Version uint64
+
+const (
+	Version0 Version = iota
+	Version1
+)
+
+// State is implemented by every versioned subnet-actor state layout.
+// Upgrades that change the on-chain layout (new slashing tables, new
+// consensus enums, ...) land as a new version package implementing State,
+// rather than mutating the fields of a layout that's already deployed.
+type State interface {
+	// GetCheckpoint gets a checkpoint from its index.
+	GetCheckpoint(s adt.Store, epoch abi.ChainEpoch) (*schema.Checkpoint, bool, error)
+	// FlushCheckpoint persists ch as the checkpoint for its epoch.
+	FlushCheckpoint(rt runtime.Runtime, ch *schema.Checkpoint)
+	// EpochCheckpoint returns the checkpoint for the current signing window.
+	EpochCheckpoint(rt runtime.Runtime) (*schema.Checkpoint, bool, error)
+	// PrevCheckCid returns the Cid of the previously committed checkpoint.
+	PrevCheckCid(rt runtime.Runtime) (cid.Cid, error)
+	// GetWindowChecks returns the recorded vote for checkCid, if any.
+	GetWindowChecks(s adt.Store, checkCid cid.Cid) (*v0.CheckVotes, bool, error)
+	// FlushWindowChecks persists w as the vote record for checkCid,
+	// returning any slashing refunds/burn for the caller to pay out via
+	// rt.Send after its own rt.StateTransaction closes.
+	FlushWindowChecks(rt runtime.Runtime, checkCid cid.Cid, w *v0.CheckVotes) ([]v0.SlashRefund, abi.TokenAmount)
+	// IsMiner reports whether addr currently has mining rights in the subnet.
+	IsMiner(addr address.Address) bool
+}
+
+// Load reads the subnet actor's state at root under the layout named by
+// version. Callers that don't yet know the version (e.g. they're about to
+// decide whether to migrate) should read it off the actor's own version
+// field rather than defaulting to Version0.
+func Load(store adt.Store, root cid.Cid, version Version) (State, error) {
+	switch version {
+	case Version0:
+		st := new(v0.SubnetState)
+		if err := store.Get(store.Context(), root, st); err != nil {
+			return nil, xerrors.Errorf("loading v0 subnet state: %w", err)
+		}
+		return st, nil
+	case Version1:
+		st := new(v1.SubnetState)
+		if err := store.Get(store.Context(), root, st); err != nil {
+			return nil, xerrors.Errorf("loading v1 subnet state: %w", err)
+		}
+		return st, nil
+	default:
+		return nil, xerrors.Errorf("unsupported subnet state version %d", version)
+	}
+}
@@ -0,0 +1,88 @@
+package v0
+
+import (
+	"testing"
+
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+)
+
+func cidForTest(t *testing.T) cid.Cid {
+	h, err := mh.Sum([]byte("checkpoint"), mh.SHA2_256, -1)
+	require.NoError(t, err)
+	return cid.NewCidV1(cid.DagCBOR, h)
+}
+
+type fakeBLSVerifier struct {
+	ok bool
+}
+
+func (f fakeBLSVerifier) VerifyAggregate(sig []byte, digest []byte, pubKeys [][]byte) bool {
+	return f.ok
+}
+
+func blsAddr(t *testing.T, payload byte) address.Address {
+	raw := make([]byte, 48)
+	raw[0] = payload
+	a, err := address.NewBLSAddress(raw)
+	require.NoError(t, err)
+	return a
+}
+
+func TestCheckVotesVoterAddrsPrefersSigners(t *testing.T) {
+	m0, m1, m2 := blsAddr(t, 0), blsAddr(t, 1), blsAddr(t, 2)
+	miners := []address.Address{m0, m1, m2}
+
+	bf := bitfield.NewFromSet([]uint64{0, 2})
+	w := &CheckVotes{Signers: bf}
+
+	voters, err := w.VoterAddrs(miners)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []address.Address{m0, m2}, voters)
+}
+
+func TestCheckVotesVoterAddrsFallsBackToLegacyMiners(t *testing.T) {
+	m0 := blsAddr(t, 0)
+	w := &CheckVotes{Miners: []address.Address{m0}}
+
+	voters, err := w.VoterAddrs(nil)
+	require.NoError(t, err)
+	require.Equal(t, []address.Address{m0}, voters)
+}
+
+func TestCheckVotesExtendsRejectsNonMonotonicVotes(t *testing.T) {
+	prev := &CheckVotes{Signers: bitfield.NewFromSet([]uint64{0, 1})}
+	superset := &CheckVotes{Signers: bitfield.NewFromSet([]uint64{0, 1, 2})}
+	disjoint := &CheckVotes{Signers: bitfield.NewFromSet([]uint64{0, 3})}
+
+	ok, err := superset.Extends(prev)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = disjoint.Extends(prev)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestVerifyAggSigRejectsNonBLSSigner(t *testing.T) {
+	idAddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	st := &SubnetState{Miners: []address.Address{idAddr}}
+	w := &CheckVotes{AggSig: []byte("sig"), Signers: bitfield.NewFromSet([]uint64{0})}
+
+	err = st.VerifyAggSig(fakeBLSVerifier{ok: true}, cidForTest(t), w)
+	require.Error(t, err)
+}
+
+func TestVerifyAggSigHonorsVerifierResult(t *testing.T) {
+	m0 := blsAddr(t, 0)
+	st := &SubnetState{Miners: []address.Address{m0}}
+	w := &CheckVotes{AggSig: []byte("sig"), Signers: bitfield.NewFromSet([]uint64{0})}
+
+	require.NoError(t, st.VerifyAggSig(fakeBLSVerifier{ok: true}, cidForTest(t), w))
+	require.Error(t, st.VerifyAggSig(fakeBLSVerifier{ok: false}, cidForTest(t), w))
+}
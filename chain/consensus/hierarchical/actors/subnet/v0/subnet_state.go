@@ -0,0 +1,460 @@
+// Package v0 holds the original subnet-actor state layout. It's kept as its
+// own version package (rather than a "subnet" package that changes shape
+// over time) so subnets that were instantiated before a later upgrade still
+// decode correctly; see the parent subnet package's State interface and
+// Load for how a version is selected.
+package v0
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/types"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/runtime"
+	"github.com/filecoin-project/specs-actors/v6/actors/util/adt"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// SignatureThreshold that determines the number of votes from
+	// total number of miners expected to propagate a checkpoint to
+	// SCA
+	SignatureThreshold = float32(0.66)
+)
+
+// QuorumPolicy determines how majorityVote counts votes for a checkpoint.
+type QuorumPolicy uint64
+
+const (
+	// QuorumCount tallies votes by number of miners, ignoring stake. This is
+	// the original behavior and the zero value, so a SubnetState serialized
+	// before QuorumPolicy existed keeps voting exactly as it did.
+	QuorumCount QuorumPolicy = iota
+	// QuorumStakeWeighted tallies votes by looking up each voter's balance
+	// in the Stake BalanceTable and comparing the sum against TotalStake,
+	// at the subnet's configured QuorumThreshold.
+	QuorumStakeWeighted
+	// QuorumPowerTwoThirds is QuorumStakeWeighted pinned to the classical
+	// BFT 2/3 threshold, irrespective of QuorumThreshold.
+	QuorumPowerTwoThirds
+)
+
+var (
+	// MinSubnetStake required to create a new subnet
+	MinSubnetStake = abi.NewTokenAmount(1e18)
+
+	// MinMinerStake is the minimum take required for a
+	// miner to be granted mining rights in the subnet and join it.
+	MinMinerStake = abi.NewTokenAmount(1e18)
+
+	// LeavingFee Penalization
+	// Coefficient divided to miner stake when leaving a subnet.
+	// NOTE: This is currently set to 1, i.e., the miner recovers
+	// its full stake. This may change once cryptoecon is figured out.
+	// We'll need to decide what to do with the leftover stake, if to
+	// burn it or keep it until the subnet is full killed.
+	LeavingFeeCoeff = big.NewInt(1)
+
+	// LeavingFeeCoeffOnSlash is the coefficient divided into a slashed
+	// miner's stake instead of LeavingFeeCoeff: equivocating on a
+	// checkpoint vote costs the miner half their stake, rather than the
+	// full refund a voluntary departure gets.
+	LeavingFeeCoeffOnSlash = big.NewInt(2)
+)
+
+// ConsensusType for subnet
+type ConsensusType uint64
+
+// List of supported/implemented consensus for subnets.
+const (
+	Delegated ConsensusType = iota
+	PoW
+	// PoS is stake-weighted consensus: voting power for both block
+	// production and checkpoint finalization follows each miner's stake
+	// rather than a flat one-miner-one-vote count.
+	PoS
+	// Tendermint is BFT consensus in the classical sense: checkpoints
+	// require a 2/3 stake-weighted quorum and the subnet enforces a
+	// minimum miner count so it can tolerate a Byzantine minority.
+	Tendermint
+	// FilecoinEC is Filecoin's Expected Consensus, for subnets that want
+	// the same leader-election rules as the root chain.
+	FilecoinEC
+)
+
+// SubnetStatus describes in what state in its lifecycle a subnet is.
+type Status uint64
+
+const (
+	Instantiated Status = iota // Waiting to onboard minimum stake to register in SCA
+	Active                     // Active and operating
+	Inactive                   // Inactive for lack of stake
+	Terminating                // Waiting for everyone to take their funds back and close the subnet
+	Killed                     // Not active anymore.
+
+)
+
+type SubnetState struct {
+	Name      string
+	ParentID  hierarchical.SubnetID
+	Consensus ConsensusType
+	// Minimum stake required by new joiners.
+	MinMinerStake abi.TokenAmount
+	// NOTE: Consider adding miners list as AMT
+	Miners     []address.Address
+	TotalStake abi.TokenAmount
+	Stake      cid.Cid // BalanceTable with the distribution of stake by miners
+	// State of the subnet
+	Status Status
+	// Genesis bootstrap for the subnet. This is created
+	// when the subnet is generated.
+	Genesis     []byte
+	CheckPeriod abi.ChainEpoch
+	// Checkpoints submit to SubnetActor per epoch
+	Checkpoints cid.Cid // HAMT[epoch]Checkpoint
+	// WindowChecks
+	WindowChecks cid.Cid // HAMT[cid]CheckVotes
+
+	// Quorum selects how majorityVote tallies votes for a checkpoint.
+	// Defaults to QuorumCount so subnets instantiated before this field
+	// existed keep their original count-based behavior.
+	Quorum QuorumPolicy
+	// QuorumThreshold is the fraction of votes (by whatever Quorum weighs
+	// them in) required to finalize a checkpoint. Defaults to
+	// SignatureThreshold.
+	QuorumThreshold float32
+}
+
+type CheckVotes struct {
+	// Miners is the legacy per-vote list of addresses. It's kept so a
+	// subnet that hasn't upgraded its miners to BLS-signed votes yet keeps
+	// working; new votes should populate AggSig/Signers instead, which
+	// scale far better with subnet size since they don't grow the stored
+	// HAMT entry linearly with the number of voters.
+	Miners []address.Address
+
+	// AggSig is the BLS aggregate signature over the checkpoint's Cid,
+	// combining every miner named in Signers.
+	AggSig []byte
+	// Signers is a bitfield of indexes into SubnetState.Miners identifying
+	// who contributed to AggSig.
+	Signers bitfield.BitField
+}
+
+// VoterAddrs resolves the miners that voted for w, preferring the compact
+// Signers bitfield when it has been populated and falling back to the
+// legacy Miners address list otherwise.
+func (w *CheckVotes) VoterAddrs(miners []address.Address) ([]address.Address, error) {
+	empty, err := w.Signers.IsEmpty()
+	if err != nil {
+		return nil, xerrors.Errorf("checking signers bitfield: %w", err)
+	}
+	if empty {
+		return w.Miners, nil
+	}
+
+	idxs, err := w.Signers.All(uint64(len(miners)))
+	if err != nil {
+		return nil, xerrors.Errorf("expanding signers bitfield: %w", err)
+	}
+	out := make([]address.Address, len(idxs))
+	for i, idx := range idxs {
+		if idx >= uint64(len(miners)) {
+			return nil, xerrors.Errorf("signer index %d out of range for %d miners", idx, len(miners))
+		}
+		out[i] = miners[idx]
+	}
+	return out, nil
+}
+
+// Extends reports whether w's Signers is a superset of prev's, i.e. w only
+// ever adds votes to a checkpoint's aggregate and never drops one that was
+// previously recorded.
+func (w *CheckVotes) Extends(prev *CheckVotes) (bool, error) {
+	missing, err := bitfield.SubtractBitField(prev.Signers, w.Signers)
+	if err != nil {
+		return false, xerrors.Errorf("diffing signers bitfields: %w", err)
+	}
+	return missing.IsEmpty()
+}
+
+// BLSAggregateVerifier checks a BLS aggregate signature. It's an interface
+// rather than a direct dependency on a specific BLS backend so SCA/subnet
+// unit tests can supply a fake; production callers wire one backed by
+// filecoin-ffi.
+type BLSAggregateVerifier interface {
+	VerifyAggregate(sig []byte, digest []byte, pubKeys [][]byte) bool
+}
+
+// VerifyAggSig checks that wch.AggSig is a valid BLS aggregate signature
+// over checkCid by the miners indexed in wch.Signers. It fails closed: any
+// non-BLS signer, out-of-range index, or verifier rejection is an error.
+func (st *SubnetState) VerifyAggSig(v BLSAggregateVerifier, checkCid cid.Cid, wch *CheckVotes) error {
+	empty, err := wch.Signers.IsEmpty()
+	if err != nil {
+		return xerrors.Errorf("checking signers bitfield: %w", err)
+	}
+	if empty || len(wch.AggSig) == 0 {
+		return xerrors.Errorf("no aggregate signature to verify")
+	}
+
+	signers, err := wch.VoterAddrs(st.Miners)
+	if err != nil {
+		return xerrors.Errorf("resolving signers: %w", err)
+	}
+
+	pubKeys := make([][]byte, len(signers))
+	for i, addr := range signers {
+		if addr.Protocol() != address.BLS {
+			return xerrors.Errorf("miner %s is not a BLS address, can't verify aggregate", addr)
+		}
+		pubKeys[i] = addr.Payload()
+	}
+
+	if !v.VerifyAggregate(wch.AggSig, checkCid.Bytes(), pubKeys) {
+		return xerrors.Errorf("aggregate signature verification failed for checkpoint %s", checkCid)
+	}
+	return nil
+}
+
+// majorityVote reports whether wch has accumulated enough votes to finalize
+// its checkpoint, under st's configured Quorum policy.
+func (st *SubnetState) majorityVote(s adt.Store, wch *CheckVotes) (bool, error) {
+	threshold := st.QuorumThreshold
+	if threshold == 0 {
+		threshold = SignatureThreshold
+	}
+
+	voters, err := wch.VoterAddrs(st.Miners)
+	if err != nil {
+		return false, xerrors.Errorf("resolving voters: %w", err)
+	}
+
+	switch st.Quorum {
+	case QuorumStakeWeighted, QuorumPowerTwoThirds:
+		if st.Quorum == QuorumPowerTwoThirds {
+			threshold = SignatureThreshold
+		}
+		voted, err := st.votingStake(s, voters)
+		if err != nil {
+			return false, xerrors.Errorf("computing voting stake: %w", err)
+		}
+		if st.TotalStake.IsZero() {
+			return false, nil
+		}
+		frac, _ := big.Div(big.Mul(voted, big.NewInt(1e9)), st.TotalStake).Float64()
+		return float32(frac/1e9) >= threshold, nil
+	default: // QuorumCount
+		return float32(len(voters))/float32(len(st.Miners)) >= threshold, nil
+	}
+}
+
+// votingStake sums the balances of miners from the Stake BalanceTable.
+func (st *SubnetState) votingStake(s adt.Store, miners []address.Address) (big.Int, error) {
+	table, err := adt.AsBalanceTable(s, st.Stake)
+	if err != nil {
+		return big.Zero(), xerrors.Errorf("loading stake balance table: %w", err)
+	}
+
+	sum := big.Zero()
+	for _, m := range miners {
+		bal, err := table.Get(m)
+		if err != nil {
+			return big.Zero(), xerrors.Errorf("getting stake for %s: %w", m, err)
+		}
+		sum = big.Add(sum, bal)
+	}
+	return sum, nil
+}
+func ConstructSubnetState(store adt.Store, params *ConstructParams) (*SubnetState, error) {
+	emptyStakeCid, err := adt.StoreEmptyMap(store, adt.BalanceTableBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create stakes balance table: %w", err)
+	}
+	emptyCheckpointsMapCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty map: %w", err)
+	}
+
+	/* Initialize AMT of miners.
+	emptyArr, err := adt.MakeEmptyArray(adt.AsStore(rt), LaneStatesAmtBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to create empty array")
+	emptyArrCid, err := emptyArr.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to persist empty array")
+	*/
+
+	parentID := hierarchical.SubnetID(params.NetworkName)
+
+	quorumThreshold := params.QuorumThreshold
+	if quorumThreshold == 0 {
+		quorumThreshold = SignatureThreshold
+	}
+
+	st := &SubnetState{
+		ParentID:        parentID,
+		Consensus:       params.Consensus,
+		MinMinerStake:   params.MinMinerStake,
+		Miners:          make([]address.Address, 0),
+		Stake:           emptyStakeCid,
+		Status:          Instantiated,
+		CheckPeriod:     params.CheckPeriod,
+		Checkpoints:     emptyCheckpointsMapCid,
+		Quorum:          params.Quorum,
+		QuorumThreshold: quorumThreshold,
+	}
+
+	err = st.emptyWindowChecks(store)
+	if err != nil {
+		return nil, err
+	}
+
+	return st, nil
+}
+
+func (st *SubnetState) emptyWindowChecks(store adt.Store) error {
+	var err error
+	st.WindowChecks, err = adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	return err
+}
+
+// EpochCheckpoint returns the checkpoint for the current signing window (if any).
+func (st *SubnetState) EpochCheckpoint(rt runtime.Runtime) (*schema.Checkpoint, bool, error) {
+	chEpoch := types.CheckpointEpoch(rt.CurrEpoch(), st.CheckPeriod)
+	return st.GetCheckpoint(adt.AsStore(rt), chEpoch)
+}
+
+// PrevCheckCid returns the Cid of the previously committed checkpoint
+func (st *SubnetState) PrevCheckCid(rt runtime.Runtime) (cid.Cid, error) {
+	chEpoch := types.CheckpointEpoch(rt.CurrEpoch(), st.CheckPeriod)
+	ep := chEpoch - st.CheckPeriod
+	// If we are in the first period.
+	if ep < 0 {
+		return schema.NoPreviousCheck, nil
+	}
+	ch, found, err := st.GetCheckpoint(adt.AsStore(rt), ep)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if !found {
+		// TODO: We could optionally return an error here.
+		return schema.NoPreviousCheck, nil
+	}
+	return ch.Cid()
+}
+
+// GetCheckpoint gets a checkpoint from its index
+func (st *SubnetState) GetCheckpoint(s adt.Store, epoch abi.ChainEpoch) (*schema.Checkpoint, bool, error) {
+	checkpoints, err := adt.AsMap(s, st.Checkpoints, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to load checkpoint: %w", err)
+	}
+	return getCheckpoint(checkpoints, epoch)
+}
+
+func getCheckpoint(checkpoints *adt.Map, epoch abi.ChainEpoch) (*schema.Checkpoint, bool, error) {
+	var out schema.Checkpoint
+	found, err := checkpoints.Get(abi.UIntKey(uint64(epoch)), &out)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to get checkpoint for epoch %v: %w", epoch, err)
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return &out, true, nil
+}
+
+func (st *SubnetState) FlushCheckpoint(rt runtime.Runtime, ch *schema.Checkpoint) {
+	// Update subnet in the list of checkpoints.
+	checks, err := adt.AsMap(adt.AsStore(rt), st.Checkpoints, builtin.DefaultHamtBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load state for checkpoints")
+	err = checks.Put(abi.UIntKey(uint64(ch.Data.Epoch)), ch)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to put checkpoint in map")
+	// Flush checkpoints
+	st.Checkpoints, err = checks.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush checkpoints")
+}
+
+func (st *SubnetState) GetWindowChecks(s adt.Store, checkCid cid.Cid) (*CheckVotes, bool, error) {
+	checks, err := adt.AsMap(s, st.WindowChecks, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to load windowCheck: %w", err)
+	}
+
+	var out CheckVotes
+	found, err := checks.Get(abi.CidKey(checkCid), &out)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to get windowCheck for Cid %v: %w", checkCid, err)
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return &out, true, nil
+}
+
+// SlashRefund is the stake a slashed miner keeps, which the caller must
+// refund to Addr via rt.Send once its own state transaction has closed,
+// since FlushWindowChecks only has an adt.Store while it runs and can't
+// send funds itself. v0 never slashes, so its FlushWindowChecks always
+// returns a nil slice; later layouts with equivocation detection (v1's)
+// populate it.
+type SlashRefund struct {
+	Addr address.Address
+	Kept abi.TokenAmount
+}
+
+// FlushWindowChecks persists w as the vote record for checkCid. If a vote
+// was already recorded for checkCid and both it and w carry a BLS-aggregated
+// Signers bitfield, w is required to extend the previous one (i.e. only add
+// signers) so a stale or forged partial aggregate can't overwrite a
+// healthier one already on the path to quorum.
+//
+// It returns refunds/burn for the caller to pay out via rt.Send after its
+// own rt.StateTransaction closes; FlushWindowChecks itself only touches
+// adt.Store, so it's safe to call from inside one.
+func (st *SubnetState) FlushWindowChecks(rt runtime.Runtime, checkCid cid.Cid, w *CheckVotes) ([]SlashRefund, abi.TokenAmount) {
+	checks, err := adt.AsMap(adt.AsStore(rt), st.WindowChecks, builtin.DefaultHamtBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load state for windowChecks")
+
+	var prev CheckVotes
+	found, err := checks.Get(abi.CidKey(checkCid), &prev)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load previous windowCheck")
+	if found {
+		prevEmpty, err := prev.Signers.IsEmpty()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to inspect previous signers")
+		newEmpty, err := w.Signers.IsEmpty()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to inspect new signers")
+		if !prevEmpty && !newEmpty {
+			extends, err := w.Extends(&prev)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to compare signer bitfields")
+			if !extends {
+				rt.Abortf(exitcode.ErrIllegalArgument, "new vote doesn't extend the previously recorded aggregate for this checkpoint")
+			}
+		}
+	}
+
+	err = checks.Put(abi.CidKey(checkCid), w)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to put windowCheck in map")
+	// Flush windowCheck
+	st.WindowChecks, err = checks.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush windowChecks")
+	return nil, big.Zero()
+}
+
+func (st *SubnetState) IsMiner(addr address.Address) bool {
+	return hasMiner(addr, st.Miners)
+}
+
+func hasMiner(addr address.Address, miners []address.Address) bool {
+	for _, a := range miners {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
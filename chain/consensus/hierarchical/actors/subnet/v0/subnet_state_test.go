@@ -0,0 +1,87 @@
+package v0
+
+import (
+	"context"
+	"testing"
+
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v6/actors/util/adt"
+
+	"github.com/filecoin-project/lotus/blockstore"
+)
+
+func newTestStore(t *testing.T) adt.Store {
+	bs := blockstore.NewMemorySync()
+	return adt.WrapStore(context.Background(), cbor.NewCborStore(bs))
+}
+
+func mustAddr(t *testing.T, s string) address.Address {
+	a, err := address.NewFromString(s)
+	require.NoError(t, err)
+	return a
+}
+
+// TestMajorityVoteStakeWeighted checks that two high-stake miners can
+// finalize a checkpoint that a dust-stake miner cannot, under
+// QuorumStakeWeighted.
+func TestMajorityVoteStakeWeighted(t *testing.T) {
+	s := newTestStore(t)
+
+	whale1 := mustAddr(t, "t01000")
+	whale2 := mustAddr(t, "t01001")
+	dust := mustAddr(t, "t01002")
+
+	stakeCid, err := adt.StoreEmptyMap(s, adt.BalanceTableBitwidth)
+	require.NoError(t, err)
+	table, err := adt.AsBalanceTable(s, stakeCid)
+	require.NoError(t, err)
+	require.NoError(t, table.AddCreate(whale1, big.NewInt(45)))
+	require.NoError(t, table.AddCreate(whale2, big.NewInt(45)))
+	require.NoError(t, table.AddCreate(dust, big.NewInt(10)))
+	stakeCid, err = table.Root()
+	require.NoError(t, err)
+
+	st := &SubnetState{
+		Miners:          []address.Address{whale1, whale2, dust},
+		TotalStake:      big.NewInt(100),
+		Stake:           stakeCid,
+		Quorum:          QuorumStakeWeighted,
+		QuorumThreshold: SignatureThreshold,
+	}
+
+	// The two whales alone clear 2/3 of stake.
+	ok, err := st.majorityVote(s, &CheckVotes{Miners: []address.Address{whale1, whale2}})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// The dust-stake miner alone can't: dust stake is only 10% of
+	// TotalStake.
+	ok, err = st.majorityVote(s, &CheckVotes{Miners: []address.Address{dust}})
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+// TestMajorityVoteCountDefault checks that a zero-value Quorum (as produced
+// by loading a SubnetState serialized before QuorumPolicy existed) still
+// tallies by raw miner count, matching the pre-migration behavior.
+func TestMajorityVoteCountDefault(t *testing.T) {
+	s := newTestStore(t)
+
+	m1 := mustAddr(t, "t01000")
+	m2 := mustAddr(t, "t01001")
+	m3 := mustAddr(t, "t01002")
+
+	st := &SubnetState{Miners: []address.Address{m1, m2, m3}}
+
+	ok, err := st.majorityVote(s, &CheckVotes{Miners: []address.Address{m1, m2}})
+	require.NoError(t, err)
+	require.True(t, ok) // 2/3 >= default SignatureThreshold
+
+	ok, err = st.majorityVote(s, &CheckVotes{Miners: []address.Address{m1}})
+	require.NoError(t, err)
+	require.False(t, ok)
+}
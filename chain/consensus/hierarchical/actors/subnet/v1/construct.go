@@ -0,0 +1,95 @@
+package v1
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/util/adt"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/consensus"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/consensus/bft"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/consensus/pos"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/consensus/pow"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+)
+
+// PolicyFor returns the consensus.Policy backing t, configured with
+// minMinerStake (the subnet's params.MinMinerStake). It's the single place
+// that maps a ConsensusType onto a concrete policy implementation, so
+// adding a new ConsensusType means adding one case here plus the
+// subpackage implementing it.
+func PolicyFor(t v0.ConsensusType, minMinerStake abi.TokenAmount) (consensus.Policy, error) {
+	switch t {
+	case v0.Delegated:
+		// Delegated has no consensus-level join/leave/quorum rules of its
+		// own: a single trusted operator produces blocks, so the pow
+		// policy's count-based checkpoint quorum is a reasonable
+		// default for the window-check voting SubnetState still does.
+		return pow.New(minMinerStake), nil
+	case v0.PoW:
+		return pow.New(minMinerStake), nil
+	case v0.PoS:
+		return pos.New(minMinerStake, 0), nil
+	case v0.Tendermint:
+		return bft.New(minMinerStake), nil
+	case v0.FilecoinEC:
+		// FilecoinEC reuses the stake-weighted pos policy: EC's own
+		// leader-election is handled above the subnet actor, but
+		// checkpoint finalization still needs a quorum rule, and
+		// stake-weighted matches how EC already weighs power.
+		return pos.New(minMinerStake, 0), nil
+	default:
+		return nil, xerrors.Errorf("unknown consensus type %d", t)
+	}
+}
+
+// ConstructSubnetState builds the v1 state for a newly created subnet,
+// selecting its consensus.Policy from params.Consensus and deriving
+// Quorum/QuorumThreshold from it instead of trusting the caller to set
+// them consistently with the chosen consensus.
+func ConstructSubnetState(store adt.Store, params *v0.ConstructParams) (*SubnetState, error) {
+	emptyStakeCid, err := adt.StoreEmptyMap(store, adt.BalanceTableBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create stakes balance table: %w", err)
+	}
+	emptyCheckpointsMapCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty map: %w", err)
+	}
+	emptyWindowChecksCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty map: %w", err)
+	}
+	emptySlashedMinersCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty map: %w", err)
+	}
+	emptyEpochChecksCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty map: %w", err)
+	}
+
+	policy, err := PolicyFor(params.Consensus, params.MinMinerStake)
+	if err != nil {
+		return nil, xerrors.Errorf("selecting consensus policy: %w", err)
+	}
+	quorum, quorumThreshold := policy.Quorum()
+
+	return &SubnetState{
+		ParentID:        hierarchical.SubnetID(params.NetworkName),
+		Consensus:       params.Consensus,
+		MinMinerStake:   policy.MinMinerStake(),
+		Miners:          make([]address.Address, 0),
+		Stake:           emptyStakeCid,
+		Status:          v0.Instantiated,
+		CheckPeriod:     params.CheckPeriod,
+		Checkpoints:     emptyCheckpointsMapCid,
+		WindowChecks:    emptyWindowChecksCid,
+		Quorum:          quorum,
+		QuorumThreshold: quorumThreshold,
+		SlashedMiners:   emptySlashedMinersCid,
+		EpochChecks:     emptyEpochChecksCid,
+	}, nil
+}
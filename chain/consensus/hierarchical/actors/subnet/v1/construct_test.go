@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+)
+
+func TestPolicyForMapsEachConsensusType(t *testing.T) {
+	stake := abi.NewTokenAmount(1)
+
+	for _, tc := range []struct {
+		t    v0.ConsensusType
+		name string
+	}{
+		{v0.Delegated, "pow"},
+		{v0.PoW, "pow"},
+		{v0.PoS, "pos"},
+		{v0.Tendermint, "bft"},
+		{v0.FilecoinEC, "pos"},
+	} {
+		p, err := PolicyFor(tc.t, stake)
+		require.NoError(t, err)
+		require.Equal(t, tc.name, p.Name())
+	}
+}
+
+func TestPolicyForRejectsUnknownConsensusType(t *testing.T) {
+	_, err := PolicyFor(v0.ConsensusType(99), abi.NewTokenAmount(1))
+	require.Error(t, err)
+}
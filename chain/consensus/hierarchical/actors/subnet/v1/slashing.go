@@ -0,0 +1,186 @@
+package v1
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/util/adt"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+)
+
+// EpochCheckSet is the value stored per epoch in EpochChecks: the distinct
+// checkCids a vote has been recorded for at that epoch.
+type EpochCheckSet struct {
+	Cids []cid.Cid
+}
+
+// SlashRecord is the evidence kept for a slashed miner, so a party that
+// reports the same equivocation twice can't have it paid out twice.
+type SlashRecord struct {
+	Epoch  abi.ChainEpoch
+	CheckA cid.Cid
+	CheckB cid.Cid
+}
+
+// IsSlashed reports whether addr has already been slashed.
+func (st *SubnetState) IsSlashed(s adt.Store, addr address.Address) (bool, error) {
+	slashed, err := adt.AsMap(s, st.SlashedMiners, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return false, err
+	}
+	var rec SlashRecord
+	return slashed.Get(abi.AddrKey(addr), &rec)
+}
+
+// recordEpochCheck adds checkCid to the set of checkCids seen for epoch and
+// returns the other checkCids already recorded for that epoch, i.e. votes
+// competing with checkCid for the same checkpoint slot.
+func (st *SubnetState) recordEpochCheck(s adt.Store, epoch abi.ChainEpoch, checkCid cid.Cid) ([]cid.Cid, error) {
+	checks, err := adt.AsMap(s, st.EpochChecks, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load state for epochChecks: %w", err)
+	}
+
+	var set EpochCheckSet
+	if _, err := checks.Get(abi.UIntKey(uint64(epoch)), &set); err != nil {
+		return nil, xerrors.Errorf("failed to get epochChecks entry: %w", err)
+	}
+
+	others := make([]cid.Cid, 0, len(set.Cids))
+	for _, c := range set.Cids {
+		if c != checkCid {
+			others = append(others, c)
+		}
+	}
+	if len(others) < len(set.Cids) {
+		// checkCid is already recorded for this epoch.
+		return others, nil
+	}
+
+	set.Cids = append(set.Cids, checkCid)
+	if err := checks.Put(abi.UIntKey(uint64(epoch)), &set); err != nil {
+		return nil, xerrors.Errorf("failed to put epochChecks entry: %w", err)
+	}
+	st.EpochChecks, err = checks.Root()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to flush epochChecks: %w", err)
+	}
+
+	return others, nil
+}
+
+// detectEquivocation looks for a miner that signed both w (for checkCid)
+// and a vote already recorded for a different checkCid at the same epoch,
+// and slashes anyone it finds before w itself is persisted. It returns a
+// refund for every miner slashed and the total burned across all of them,
+// for the caller to pay out via rt.Send.
+func (st *SubnetState) detectEquivocation(s adt.Store, epoch abi.ChainEpoch, checkCid cid.Cid, w *v0.CheckVotes) ([]v0.SlashRefund, abi.TokenAmount, error) {
+	others, err := st.recordEpochCheck(s, epoch, checkCid)
+	if err != nil {
+		return nil, big.Zero(), err
+	}
+	if len(others) == 0 {
+		return nil, big.Zero(), nil
+	}
+
+	voters, err := w.VoterAddrs(st.Miners)
+	if err != nil {
+		return nil, big.Zero(), xerrors.Errorf("failed to resolve voters: %w", err)
+	}
+
+	votedHere := make(map[address.Address]bool, len(voters))
+	for _, a := range voters {
+		votedHere[a] = true
+	}
+
+	var refunds []v0.SlashRefund
+	burned := big.Zero()
+	for _, other := range others {
+		otherVotes, found, err := st.GetWindowChecks(s, other)
+		if err != nil {
+			return nil, big.Zero(), xerrors.Errorf("failed to load competing windowCheck: %w", err)
+		}
+		if !found {
+			continue
+		}
+		otherVoters, err := otherVotes.VoterAddrs(st.Miners)
+		if err != nil {
+			return nil, big.Zero(), xerrors.Errorf("failed to resolve competing voters: %w", err)
+		}
+		for _, addr := range otherVoters {
+			if votedHere[addr] {
+				kept, slashBurned, err := st.SlashMiner(s, addr, epoch, checkCid, other)
+				if err != nil {
+					return nil, big.Zero(), err
+				}
+				refunds = append(refunds, v0.SlashRefund{Addr: addr, Kept: kept})
+				burned = big.Add(burned, slashBurned)
+			}
+		}
+	}
+	return refunds, burned, nil
+}
+
+// SlashMiner burns a LeavingFeeCoeffOnSlash share of addr's stake, removes
+// addr from Miners, and records the evidence in SlashedMiners so the same
+// equivocation can't be paid out twice. It returns the stake addr keeps
+// (for the caller to refund via rt.Send) and the amount burned; both are
+// zero if addr was already slashed.
+func (st *SubnetState) SlashMiner(s adt.Store, addr address.Address, epoch abi.ChainEpoch, checkA, checkB cid.Cid) (kept, burned abi.TokenAmount, err error) {
+	slashed, err := adt.AsMap(s, st.SlashedMiners, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return big.Zero(), big.Zero(), xerrors.Errorf("failed to load state for slashedMiners: %w", err)
+	}
+
+	var existing SlashRecord
+	already, err := slashed.Get(abi.AddrKey(addr), &existing)
+	if err != nil {
+		return big.Zero(), big.Zero(), xerrors.Errorf("failed to check slashedMiners: %w", err)
+	}
+	if already {
+		return big.Zero(), big.Zero(), nil
+	}
+
+	table, err := adt.AsBalanceTable(s, st.Stake)
+	if err != nil {
+		return big.Zero(), big.Zero(), xerrors.Errorf("failed to load stake balance table: %w", err)
+	}
+	balance, err := table.Get(addr)
+	if err != nil {
+		return big.Zero(), big.Zero(), xerrors.Errorf("failed to get miner stake: %w", err)
+	}
+
+	kept = big.Div(balance, v0.LeavingFeeCoeffOnSlash)
+	burned = big.Sub(balance, kept)
+
+	if _, err := table.SubtractWithMinimum(addr, balance, big.Zero()); err != nil {
+		return big.Zero(), big.Zero(), xerrors.Errorf("failed to zero slashed miner's stake: %w", err)
+	}
+	st.Stake, err = table.Root()
+	if err != nil {
+		return big.Zero(), big.Zero(), xerrors.Errorf("failed to flush stake balance table: %w", err)
+	}
+	st.TotalStake = big.Sub(st.TotalStake, balance)
+
+	miners := make([]address.Address, 0, len(st.Miners))
+	for _, m := range st.Miners {
+		if m != addr {
+			miners = append(miners, m)
+		}
+	}
+	st.Miners = miners
+
+	if err := slashed.Put(abi.AddrKey(addr), &SlashRecord{Epoch: epoch, CheckA: checkA, CheckB: checkB}); err != nil {
+		return big.Zero(), big.Zero(), xerrors.Errorf("failed to record slashed miner: %w", err)
+	}
+	st.SlashedMiners, err = slashed.Root()
+	if err != nil {
+		return big.Zero(), big.Zero(), xerrors.Errorf("failed to flush slashedMiners: %w", err)
+	}
+
+	return kept, burned, nil
+}
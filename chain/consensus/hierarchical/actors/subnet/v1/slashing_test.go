@@ -0,0 +1,267 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/util/adt"
+
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+)
+
+func newTestStore(t *testing.T) adt.Store {
+	bs := blockstore.NewMemorySync()
+	return adt.WrapStore(context.Background(), cbor.NewCborStore(bs))
+}
+
+func mustAddr(t *testing.T, s string) address.Address {
+	a, err := address.NewFromString(s)
+	require.NoError(t, err)
+	return a
+}
+
+func checkCidForTest(t *testing.T, payload string) cid.Cid {
+	h, err := mh.Sum([]byte(payload), mh.SHA2_256, -1)
+	require.NoError(t, err)
+	return cid.NewCidV1(cid.DagCBOR, h)
+}
+
+// newSlashTestState builds a SubnetState with the given equally-staked
+// miners and every HAMT field bootstrapped, ready to exercise
+// detectEquivocation/SlashMiner directly.
+func newSlashTestState(t *testing.T, s adt.Store, miners []address.Address) *SubnetState {
+	stakeCid, err := adt.StoreEmptyMap(s, adt.BalanceTableBitwidth)
+	require.NoError(t, err)
+	table, err := adt.AsBalanceTable(s, stakeCid)
+	require.NoError(t, err)
+	total := big.Zero()
+	for _, m := range miners {
+		require.NoError(t, table.AddCreate(m, big.NewInt(30)))
+		total = big.Add(total, big.NewInt(30))
+	}
+	stakeCid, err = table.Root()
+	require.NoError(t, err)
+
+	windowChecksCid, err := adt.StoreEmptyMap(s, builtin.DefaultHamtBitwidth)
+	require.NoError(t, err)
+	epochChecksCid, err := adt.StoreEmptyMap(s, builtin.DefaultHamtBitwidth)
+	require.NoError(t, err)
+	slashedMinersCid, err := adt.StoreEmptyMap(s, builtin.DefaultHamtBitwidth)
+	require.NoError(t, err)
+
+	return &SubnetState{
+		Miners:          miners,
+		TotalStake:      total,
+		Stake:           stakeCid,
+		Quorum:          v0.QuorumStakeWeighted,
+		QuorumThreshold: v0.SignatureThreshold,
+		CheckPeriod:     10,
+		WindowChecks:    windowChecksCid,
+		EpochChecks:     epochChecksCid,
+		SlashedMiners:   slashedMinersCid,
+	}
+}
+
+func putWindowCheck(s adt.Store, st *SubnetState, checkCid cid.Cid, w *v0.CheckVotes) error {
+	checks, err := adt.AsMap(s, st.WindowChecks, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return err
+	}
+	if err := checks.Put(abi.CidKey(checkCid), w); err != nil {
+		return err
+	}
+	st.WindowChecks, err = checks.Root()
+	return err
+}
+
+// TestDetectEquivocationSlashesSharedSigner checks that a miner who votes
+// for two different checkCids at the same epoch is slashed: their stake is
+// burned down to the LeavingFeeCoeffOnSlash remainder and they're dropped
+// from Miners.
+func TestDetectEquivocationSlashesSharedSigner(t *testing.T) {
+	s := newTestStore(t)
+	culprit := mustAddr(t, "t01000")
+	honest := mustAddr(t, "t01001")
+	st := newSlashTestState(t, s, []address.Address{culprit, honest})
+
+	checkA := checkCidForTest(t, "A")
+	checkB := checkCidForTest(t, "B")
+	epoch := abi.ChainEpoch(0)
+
+	voteA := &v0.CheckVotes{Miners: []address.Address{culprit}}
+	_, _, err := st.detectEquivocation(s, epoch, checkA, voteA)
+	require.NoError(t, err)
+	require.NoError(t, putWindowCheck(s, st, checkA, voteA))
+
+	voteB := &v0.CheckVotes{Miners: []address.Address{culprit}}
+	refunds, burned, err := st.detectEquivocation(s, epoch, checkB, voteB)
+	require.NoError(t, err)
+	require.Equal(t, []v0.SlashRefund{{Addr: culprit, Kept: big.NewInt(15)}}, refunds)
+	require.Equal(t, big.NewInt(15), burned)
+
+	require.Equal(t, []address.Address{honest}, st.Miners)
+
+	slashed, err := st.IsSlashed(s, culprit)
+	require.NoError(t, err)
+	require.True(t, slashed)
+
+	table, err := adt.AsBalanceTable(s, st.Stake)
+	require.NoError(t, err)
+	bal, err := table.Get(culprit)
+	require.NoError(t, err)
+	require.True(t, bal.IsZero())
+	require.Equal(t, big.NewInt(30), st.TotalStake)
+}
+
+// TestSlashMinerIsIdempotent checks that reporting the same miner twice
+// doesn't burn their stake a second time.
+func TestSlashMinerIsIdempotent(t *testing.T) {
+	s := newTestStore(t)
+	culprit := mustAddr(t, "t01000")
+	st := newSlashTestState(t, s, []address.Address{culprit})
+
+	checkA := checkCidForTest(t, "A")
+	checkB := checkCidForTest(t, "B")
+
+	kept1, burned1, err := st.SlashMiner(s, culprit, 0, checkA, checkB)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(15), kept1)
+	require.Equal(t, big.NewInt(15), burned1)
+
+	kept2, burned2, err := st.SlashMiner(s, culprit, 0, checkA, checkB)
+	require.NoError(t, err)
+	require.True(t, kept2.IsZero())
+	require.True(t, burned2.IsZero())
+
+	// TotalStake only reflects the first burn.
+	require.Equal(t, big.NewInt(0), st.TotalStake)
+}
+
+// TestSlashedMinerStakeNoLongerCounts checks that once a contributing miner
+// is slashed mid-window, the stake a quorum computation would sum for them
+// (via the Stake balance table and TotalStake) drops to zero, rather than
+// still crediting their pre-slash share toward a later vote.
+func TestSlashedMinerStakeNoLongerCounts(t *testing.T) {
+	s := newTestStore(t)
+	culprit := mustAddr(t, "t01000")
+	whale := mustAddr(t, "t01001")
+	st := newSlashTestState(t, s, []address.Address{culprit, whale})
+
+	checkA := checkCidForTest(t, "A")
+	checkB := checkCidForTest(t, "B")
+	epoch := abi.ChainEpoch(0)
+
+	// Both miners vote for checkA, together contributing the full
+	// TotalStake toward that checkpoint's quorum.
+	joint := &v0.CheckVotes{Miners: []address.Address{culprit, whale}}
+	_, _, err := st.detectEquivocation(s, epoch, checkA, joint)
+	require.NoError(t, err)
+	require.NoError(t, putWindowCheck(s, st, checkA, joint))
+
+	stakeBefore, err := st.votingStakeForTest(s, []address.Address{culprit, whale})
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(60), stakeBefore)
+
+	// culprit then equivocates by also voting for checkB, and is slashed.
+	voteB := &v0.CheckVotes{Miners: []address.Address{culprit}}
+	_, _, err = st.detectEquivocation(s, epoch, checkB, voteB)
+	require.NoError(t, err)
+
+	slashed, err := st.IsSlashed(s, culprit)
+	require.NoError(t, err)
+	require.True(t, slashed)
+
+	// The same miner set now sums to only whale's stake: culprit's share
+	// was burned and can no longer help a vote clear quorum.
+	stakeAfter, err := st.votingStakeForTest(s, []address.Address{culprit, whale})
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(30), stakeAfter)
+}
+
+// TestFlushWindowChecksSlashesEquivocatingSigner exercises FlushWindowChecks'
+// store-only logic end to end (not just the detectEquivocation call it
+// makes internally): a miner who signs two different checkCids for the
+// same epoch is slashed, the refund/burn amounts come back out of
+// flushWindowChecks instead of being sent from inside it, and the second
+// vote is still persisted as the windowCheck for its checkCid.
+func TestFlushWindowChecksSlashesEquivocatingSigner(t *testing.T) {
+	s := newTestStore(t)
+	culprit := mustAddr(t, "t01000")
+	honest := mustAddr(t, "t01001")
+	st := newSlashTestState(t, s, []address.Address{culprit, honest})
+
+	checkA := checkCidForTest(t, "A")
+	checkB := checkCidForTest(t, "B")
+	epoch := abi.ChainEpoch(0)
+
+	voteA := &v0.CheckVotes{Miners: []address.Address{culprit}}
+	_, _, err := st.flushWindowChecks(s, epoch, checkA, voteA)
+	require.NoError(t, err)
+
+	voteB := &v0.CheckVotes{Miners: []address.Address{culprit}}
+	refunds, burned, err := st.flushWindowChecks(s, epoch, checkB, voteB)
+	require.NoError(t, err)
+	require.Equal(t, []v0.SlashRefund{{Addr: culprit, Kept: big.NewInt(15)}}, refunds)
+	require.Equal(t, big.NewInt(15), burned)
+
+	slashed, err := st.IsSlashed(s, culprit)
+	require.NoError(t, err)
+	require.True(t, slashed)
+
+	stored, found, err := st.GetWindowChecks(s, checkB)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, voteB.Miners, stored.Miners)
+}
+
+// TestFlushWindowChecksRejectsNonExtendingVote checks that flushWindowChecks
+// refuses a vote that doesn't extend a previously recorded BLS-aggregated
+// signer bitfield for the same checkCid, rather than silently overwriting
+// it with a weaker one.
+func TestFlushWindowChecksRejectsNonExtendingVote(t *testing.T) {
+	s := newTestStore(t)
+	m0 := mustAddr(t, "t01000")
+	m1 := mustAddr(t, "t01001")
+	st := newSlashTestState(t, s, []address.Address{m0, m1})
+	checkA := checkCidForTest(t, "A")
+	epoch := abi.ChainEpoch(0)
+
+	wide := bitfield.NewFromSet([]uint64{0, 1})
+	prev := &v0.CheckVotes{Signers: wide}
+	_, _, err := st.flushWindowChecks(s, epoch, checkA, prev)
+	require.NoError(t, err)
+
+	narrow := bitfield.NewFromSet([]uint64{0})
+	next := &v0.CheckVotes{Signers: narrow}
+	_, _, err = st.flushWindowChecks(s, epoch, checkA, next)
+	require.ErrorIs(t, err, errVoteDoesntExtend)
+}
+
+// votingStakeForTest mirrors v0's unexported votingStake helper, summing
+// balances from the Stake BalanceTable for the given miners.
+func (st *SubnetState) votingStakeForTest(s adt.Store, miners []address.Address) (big.Int, error) {
+	table, err := adt.AsBalanceTable(s, st.Stake)
+	if err != nil {
+		return big.Zero(), err
+	}
+	sum := big.Zero()
+	for _, m := range miners {
+		bal, err := table.Get(m)
+		if err != nil {
+			return big.Zero(), err
+		}
+		sum = big.Add(sum, bal)
+	}
+	return sum, nil
+}
@@ -0,0 +1,230 @@
+// Package v1 is the second subnet-actor state layout. It carries everything
+// v0 did plus the SlashedMiners and EpochChecks tables that equivocation
+// slashing uses; subnets created after the v1 upgrade epoch start here
+// directly, and subnets still on v0 are rewritten into this layout by
+// subnet.MigrateV0toV1 the first time they cross that epoch.
+package v1
+
+import (
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/runtime"
+	"github.com/filecoin-project/specs-actors/v6/actors/util/adt"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/subnet/v0"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/types"
+)
+
+// SubnetState is the v1 layout: every v0 field, plus SlashedMiners and
+// EpochChecks.
+type SubnetState struct {
+	Name      string
+	ParentID  hierarchical.SubnetID
+	Consensus v0.ConsensusType
+	// Minimum stake required by new joiners.
+	MinMinerStake abi.TokenAmount
+	// NOTE: Consider adding miners list as AMT
+	Miners     []address.Address
+	TotalStake abi.TokenAmount
+	Stake      cid.Cid // BalanceTable with the distribution of stake by miners
+	// State of the subnet
+	Status v0.Status
+	// Genesis bootstrap for the subnet. This is created
+	// when the subnet is generated.
+	Genesis     []byte
+	CheckPeriod abi.ChainEpoch
+	// Checkpoints submit to SubnetActor per epoch
+	Checkpoints cid.Cid // HAMT[epoch]Checkpoint
+	// WindowChecks
+	WindowChecks cid.Cid // HAMT[cid]CheckVotes
+
+	// Quorum selects how majorityVote tallies votes for a checkpoint.
+	Quorum          v0.QuorumPolicy
+	QuorumThreshold float32
+
+	// SlashedMiners is a HAMT[address]SlashRecord of miners removed from
+	// Miners for equivocating on a checkpoint vote. An address present
+	// here can't be slashed again for a new equivocation report, since
+	// they've already lost their mining rights and the relevant stake.
+	SlashedMiners cid.Cid
+
+	// EpochChecks is a HAMT[epoch]EpochCheckSet recording every distinct
+	// checkCid a vote has been recorded for at each checkpoint epoch. A
+	// window normally only ever sees one checkCid; a second one appearing
+	// for the same epoch is exactly the equivocation FlushWindowChecks'
+	// slashing detector watches for.
+	EpochChecks cid.Cid
+}
+
+// GetCheckpoint gets a checkpoint from its index. Identical to v0; the
+// Checkpoints HAMT layout hasn't changed.
+func (st *SubnetState) GetCheckpoint(s adt.Store, epoch abi.ChainEpoch) (*schema.Checkpoint, bool, error) {
+	checkpoints, err := adt.AsMap(s, st.Checkpoints, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to load checkpoint: %w", err)
+	}
+	var out schema.Checkpoint
+	found, err := checkpoints.Get(abi.UIntKey(uint64(epoch)), &out)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to get checkpoint for epoch %v: %w", epoch, err)
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return &out, true, nil
+}
+
+// FlushCheckpoint persists ch as the checkpoint for its epoch.
+func (st *SubnetState) FlushCheckpoint(rt runtime.Runtime, ch *schema.Checkpoint) {
+	checks, err := adt.AsMap(adt.AsStore(rt), st.Checkpoints, builtin.DefaultHamtBitwidth)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load state for checkpoints")
+	err = checks.Put(abi.UIntKey(uint64(ch.Data.Epoch)), ch)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to put checkpoint in map")
+	st.Checkpoints, err = checks.Root()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush checkpoints")
+}
+
+// EpochCheckpoint returns the checkpoint for the current signing window (if any).
+func (st *SubnetState) EpochCheckpoint(rt runtime.Runtime) (*schema.Checkpoint, bool, error) {
+	chEpoch := types.CheckpointEpoch(rt.CurrEpoch(), st.CheckPeriod)
+	return st.GetCheckpoint(adt.AsStore(rt), chEpoch)
+}
+
+// PrevCheckCid returns the Cid of the previously committed checkpoint.
+func (st *SubnetState) PrevCheckCid(rt runtime.Runtime) (cid.Cid, error) {
+	chEpoch := types.CheckpointEpoch(rt.CurrEpoch(), st.CheckPeriod)
+	ep := chEpoch - st.CheckPeriod
+	if ep < 0 {
+		return schema.NoPreviousCheck, nil
+	}
+	ch, found, err := st.GetCheckpoint(adt.AsStore(rt), ep)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if !found {
+		return schema.NoPreviousCheck, nil
+	}
+	return ch.Cid()
+}
+
+// GetWindowChecks returns the recorded vote for checkCid, if any.
+func (st *SubnetState) GetWindowChecks(s adt.Store, checkCid cid.Cid) (*v0.CheckVotes, bool, error) {
+	checks, err := adt.AsMap(s, st.WindowChecks, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to load windowCheck: %w", err)
+	}
+	var out v0.CheckVotes
+	found, err := checks.Get(abi.CidKey(checkCid), &out)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to get windowCheck for Cid %v: %w", checkCid, err)
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return &out, true, nil
+}
+
+// errVoteDoesntExtend is returned by flushWindowChecks when w doesn't
+// extend a previously recorded aggregate for checkCid; FlushWindowChecks
+// turns it into an ErrIllegalArgument abort.
+var errVoteDoesntExtend = xerrors.New("new vote doesn't extend the previously recorded aggregate for this checkpoint")
+
+// flushWindowChecks is the store-only logic behind FlushWindowChecks:
+// enforcing the extends-the-previous-aggregate invariant, detecting
+// equivocation, and persisting w. Splitting it out from FlushWindowChecks
+// means the slashing/refund path this method drives can be exercised
+// directly in a test, the same way detectEquivocation and SlashMiner
+// already are, rather than only through the rt-taking wrapper.
+func (st *SubnetState) flushWindowChecks(s adt.Store, epoch abi.ChainEpoch, checkCid cid.Cid, w *v0.CheckVotes) ([]v0.SlashRefund, abi.TokenAmount, error) {
+	checks, err := adt.AsMap(s, st.WindowChecks, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, big.Zero(), xerrors.Errorf("failed to load state for windowChecks: %w", err)
+	}
+
+	var prev v0.CheckVotes
+	found, err := checks.Get(abi.CidKey(checkCid), &prev)
+	if err != nil {
+		return nil, big.Zero(), xerrors.Errorf("failed to load previous windowCheck: %w", err)
+	}
+	if found {
+		prevEmpty, err := prev.Signers.IsEmpty()
+		if err != nil {
+			return nil, big.Zero(), xerrors.Errorf("failed to inspect previous signers: %w", err)
+		}
+		newEmpty, err := w.Signers.IsEmpty()
+		if err != nil {
+			return nil, big.Zero(), xerrors.Errorf("failed to inspect new signers: %w", err)
+		}
+		if !prevEmpty && !newEmpty {
+			extends, err := w.Extends(&prev)
+			if err != nil {
+				return nil, big.Zero(), xerrors.Errorf("failed to compare signer bitfields: %w", err)
+			}
+			if !extends {
+				return nil, big.Zero(), errVoteDoesntExtend
+			}
+		}
+	}
+
+	refunds, burned, err := st.detectEquivocation(s, epoch, checkCid, w)
+	if err != nil {
+		return nil, big.Zero(), xerrors.Errorf("failed to check for checkpoint equivocation: %w", err)
+	}
+
+	if err := checks.Put(abi.CidKey(checkCid), w); err != nil {
+		return nil, big.Zero(), xerrors.Errorf("failed to put windowCheck in map: %w", err)
+	}
+	st.WindowChecks, err = checks.Root()
+	if err != nil {
+		return nil, big.Zero(), xerrors.Errorf("failed to flush windowChecks: %w", err)
+	}
+
+	return refunds, burned, nil
+}
+
+// FlushWindowChecks persists w as the vote record for checkCid, enforcing
+// the same extends-the-previous-aggregate invariant as v0.
+//
+// It returns any slashing refunds/burn for the caller to pay out via
+// rt.Send after its own rt.StateTransaction closes; FlushWindowChecks
+// itself only touches adt.Store, so it's safe to call from inside one.
+func (st *SubnetState) FlushWindowChecks(rt runtime.Runtime, checkCid cid.Cid, w *v0.CheckVotes) ([]v0.SlashRefund, abi.TokenAmount) {
+	epoch := types.CheckpointEpoch(rt.CurrEpoch(), st.CheckPeriod)
+	refunds, burned, err := st.flushWindowChecks(adt.AsStore(rt), epoch, checkCid, w)
+	if err == errVoteDoesntExtend {
+		rt.Abortf(exitcode.ErrIllegalArgument, "%s", err)
+	}
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush window checks")
+
+	// Every miner slashed above had its stake removed from the Stake
+	// BalanceTable; the actual FIL backing it is the caller's
+	// responsibility to send once its own state transaction has closed,
+	// same as settleAtomicExec's refund in the SCA package.
+	return refunds, burned
+}
+
+// IsMiner reports whether addr currently has mining rights in the subnet.
+// A slashed miner is removed from Miners when it's recorded in
+// SlashedMiners, so this stays accurate without a separate check.
+func (st *SubnetState) IsMiner(addr address.Address) bool {
+	for _, a := range st.Miners {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// MinerCount implements consensus.JoinState.
+func (st *SubnetState) MinerCount() int { return len(st.Miners) }
+
+// TotalStaked implements consensus.JoinState. It's named TotalStaked,
+// rather than TotalStake, to avoid colliding with the TotalStake field.
+func (st *SubnetState) TotalStaked() abi.TokenAmount { return st.TotalStake }
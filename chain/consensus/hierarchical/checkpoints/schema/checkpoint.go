@@ -53,6 +53,13 @@ type CheckData struct {
 	PrevCheckpoint cid.Cid
 	Childs         []ChildCheck
 	XShardMsg      *MsgTreeList
+	// OracleEpoch is the CheckpointOracle signer-set version the SCA's
+	// committee was on when it attested to this checkpoint via
+	// SubmitSignedCheckpoint. It's embedded in Data (and so covered by
+	// Cid()) so a later verifier can look up which historical signer set
+	// to check a checkpoint's Signature/Signers against, rather than only
+	// being able to validate against whichever set is current right now.
+	OracleEpoch int
 }
 
 // Checkpoint data structure
@@ -61,9 +68,14 @@ type CheckData struct {
 // is what identifies a checkpoint uniquely.
 // - Signature adds the signature from a miner. According to the verifier
 // used for checkpoint this may be different things.
+// - Signers is a bitfield of indexes into the authoritative signer set for
+// the source subnet, identifying who contributed to a BLS-aggregated
+// Signature. Like Signature, it's excluded from Cid() so the checkpoint's
+// identity doesn't depend on who has signed it so far.
 type Checkpoint struct {
 	Data      CheckData
 	Signature []byte
+	Signers   []byte
 }
 
 // initCheckpointType initializes the Checkpoint schema
@@ -94,6 +106,7 @@ func initCheckpointSchema() schema.Type {
 			schema.SpawnStructField("PrevCheckpoint", "Link", false, false),
 			schema.SpawnStructField("Childs", "List_ChildCheck", false, false),
 			schema.SpawnStructField("XShardMsg", "MsgTreeList", false, true),
+			schema.SpawnStructField("OracleEpoch", "Int", false, false),
 		},
 		schema.SpawnStructRepresentationMap(nil),
 	))
@@ -101,6 +114,7 @@ func initCheckpointSchema() schema.Type {
 		[]schema.StructField{
 			schema.SpawnStructField("Data", "CheckData", false, false),
 			schema.SpawnStructField("Signature", "Bytes", false, false),
+			schema.SpawnStructField("Signers", "Bytes", false, false),
 		},
 		schema.SpawnStructRepresentationMap(nil),
 	))
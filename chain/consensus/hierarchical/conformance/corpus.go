@@ -0,0 +1,33 @@
+package conformance
+
+import (
+	"encoding/json"
+	"io"
+
+	"golang.org/x/xerrors"
+)
+
+// LoadCorpus decodes a JSON-encoded []Vector corpus from r, rejecting any
+// vector whose Version doesn't match CorpusVersion.
+func LoadCorpus(r io.Reader) ([]Vector, error) {
+	var vectors []Vector
+	if err := json.NewDecoder(r).Decode(&vectors); err != nil {
+		return nil, xerrors.Errorf("decoding corpus: %w", err)
+	}
+	for i, v := range vectors {
+		if v.Version != CorpusVersion {
+			return nil, xerrors.Errorf("vector %d (%s): unsupported corpus version %q, want %q", i, v.Description, v.Version, CorpusVersion)
+		}
+	}
+	return vectors, nil
+}
+
+// SaveCorpus JSON-encodes vectors to w.
+func SaveCorpus(w io.Writer, vectors []Vector) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(vectors); err != nil {
+		return xerrors.Errorf("encoding corpus: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package conformance
+
+import (
+	"encoding/json"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/sca"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/filecoin-project/specs-actors/v7/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// CurrWindowCheckpointParams is the Step.Params shape for the
+// "CurrWindowCheckpoint" Op.
+type CurrWindowCheckpointParams struct {
+	Epoch abi.ChainEpoch
+}
+
+// RawCheckpointParams is the Step.Params shape for the "RawCheckpoint" Op.
+type RawCheckpointParams struct {
+	Epoch abi.ChainEpoch
+}
+
+// PutCheckpointIdempotentParams is the Step.Params shape for the
+// "PutCheckpointIdempotent" Op.
+type PutCheckpointIdempotentParams struct {
+	Epoch abi.ChainEpoch
+}
+
+// Run replays v's Steps against the SCAState found at v.PreStateCid in
+// store, returning the resulting state's Cid and one Receipt per Step. It
+// doesn't itself compare against v.PostStateCid/v.Receipts - callers
+// (typically a table-driven test) do that, so a failed vector reports
+// exactly where it diverged.
+func Run(store adt.Store, v *Vector) (cid.Cid, []Receipt, error) {
+	preCid, err := cid.Decode(v.PreStateCid)
+	if err != nil {
+		return cid.Undef, nil, xerrors.Errorf("decoding pre-state cid: %w", err)
+	}
+	var st sca.SCAState
+	if err := store.Get(store.Context(), preCid, &st); err != nil {
+		return cid.Undef, nil, xerrors.Errorf("loading pre-state %s: %w", preCid, err)
+	}
+
+	receipts := make([]Receipt, 0, len(v.Steps))
+	for _, step := range v.Steps {
+		stepErr := applyStep(store, &st, step)
+		r := Receipt{Op: step.Op, OK: stepErr == nil}
+		if stepErr != nil {
+			r.Error = stepErr.Error()
+		}
+		receipts = append(receipts, r)
+	}
+
+	postCid, err := store.Put(store.Context(), &st)
+	if err != nil {
+		return cid.Undef, receipts, xerrors.Errorf("computing post-state cid: %w", err)
+	}
+	return postCid, receipts, nil
+}
+
+func applyStep(store adt.Store, st *sca.SCAState, step Step) error {
+	switch step.Op {
+	case "CurrWindowCheckpoint":
+		var p CurrWindowCheckpointParams
+		if err := json.Unmarshal(step.Params, &p); err != nil {
+			return xerrors.Errorf("decoding params: %w", err)
+		}
+		_, err := st.CurrWindowCheckpoint(store, p.Epoch)
+		return err
+	case "RawCheckpoint":
+		var p RawCheckpointParams
+		if err := json.Unmarshal(step.Params, &p); err != nil {
+			return xerrors.Errorf("decoding params: %w", err)
+		}
+		_, err := sca.RawCheckpoint(st, store, p.Epoch)
+		return err
+	case "PutCheckpointIdempotent":
+		var p PutCheckpointIdempotentParams
+		if err := json.Unmarshal(step.Params, &p); err != nil {
+			return xerrors.Errorf("decoding params: %w", err)
+		}
+		return putCheckpointTwiceAndCompare(store, st, p.Epoch)
+	default:
+		return xerrors.Errorf("unknown step op %q", step.Op)
+	}
+}
+
+// putCheckpoint mirrors SCAState.flushCheckpoint's put-then-reroot
+// sequence, reimplemented here against the public adt.Store API since
+// flushCheckpoint itself is unexported and only callable with a
+// runtime.Runtime this package doesn't have.
+func putCheckpoint(store adt.Store, st *sca.SCAState, ch *schema.Checkpoint) error {
+	checks, err := adt.AsMap(store, st.Checkpoints, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return xerrors.Errorf("loading checkpoints: %w", err)
+	}
+	if err := checks.Put(abi.UIntKey(uint64(ch.Data.Epoch)), ch); err != nil {
+		return xerrors.Errorf("putting checkpoint: %w", err)
+	}
+	root, err := checks.Root()
+	if err != nil {
+		return xerrors.Errorf("flushing checkpoints: %w", err)
+	}
+	st.Checkpoints = root
+	return nil
+}
+
+// putCheckpointTwiceAndCompare checks that storing the same window
+// checkpoint a second time is a no-op for the Checkpoints HAMT root, the
+// idempotency flushCheckpoint is relied on to have.
+func putCheckpointTwiceAndCompare(store adt.Store, st *sca.SCAState, epoch abi.ChainEpoch) error {
+	ch, err := st.CurrWindowCheckpoint(store, epoch)
+	if err != nil {
+		return xerrors.Errorf("building checkpoint template: %w", err)
+	}
+	if err := putCheckpoint(store, st, ch); err != nil {
+		return err
+	}
+	rootAfterFirst := st.Checkpoints
+	if err := putCheckpoint(store, st, ch); err != nil {
+		return err
+	}
+	if !rootAfterFirst.Equals(st.Checkpoints) {
+		return xerrors.Errorf("putting the same checkpoint twice changed the Checkpoints root: %s != %s", rootAfterFirst, st.Checkpoints)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/sca"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) adt.Store {
+	bs := blockstore.NewMemorySync()
+	return adt.WrapStore(context.Background(), cbor.NewCborStore(bs))
+}
+
+func newTestPreState(t *testing.T, store adt.Store) string {
+	st, err := sca.ConstructSCAState(store, &sca.ConstructorParams{
+		NetworkName:      "/root",
+		CheckpointPeriod: uint64(sca.DefaultCheckpointPeriod),
+	})
+	require.NoError(t, err)
+	c, err := store.Put(store.Context(), st)
+	require.NoError(t, err)
+	return c.String()
+}
+
+func mustParams(t *testing.T, p interface{}) json.RawMessage {
+	b, err := json.Marshal(p)
+	require.NoError(t, err)
+	return b
+}
+
+func TestRunCurrWindowCheckpoint(t *testing.T) {
+	store := newTestStore(t)
+	v := &Vector{
+		Version:     CorpusVersion,
+		PreStateCid: newTestPreState(t, store),
+		Steps: []Step{
+			{Op: "CurrWindowCheckpoint", Params: mustParams(t, CurrWindowCheckpointParams{Epoch: 5})},
+		},
+	}
+
+	postCid, receipts, err := Run(store, v)
+	require.NoError(t, err)
+	require.Len(t, receipts, 1)
+	require.True(t, receipts[0].OK)
+	// CurrWindowCheckpoint only reads the state, so the post-state is the
+	// pre-state.
+	require.Equal(t, v.PreStateCid, postCid.String())
+}
+
+func TestRunPutCheckpointIdempotent(t *testing.T) {
+	store := newTestStore(t)
+	v := &Vector{
+		Version:     CorpusVersion,
+		PreStateCid: newTestPreState(t, store),
+		Steps: []Step{
+			{Op: "PutCheckpointIdempotent", Params: mustParams(t, PutCheckpointIdempotentParams{Epoch: 3})},
+		},
+	}
+
+	_, receipts, err := Run(store, v)
+	require.NoError(t, err)
+	require.Len(t, receipts, 1)
+	require.True(t, receipts[0].OK, receipts[0].Error)
+}
+
+func TestRunReplayIsDeterministic(t *testing.T) {
+	store := newTestStore(t)
+	pre := newTestPreState(t, store)
+	steps := []Step{
+		{Op: "CurrWindowCheckpoint", Params: mustParams(t, CurrWindowCheckpointParams{Epoch: 5})},
+		{Op: "RawCheckpoint", Params: mustParams(t, RawCheckpointParams{Epoch: 10})},
+	}
+
+	v1 := &Vector{Version: CorpusVersion, PreStateCid: pre, Steps: steps}
+	post1, receipts1, err := Run(store, v1)
+	require.NoError(t, err)
+
+	v2 := &Vector{Version: CorpusVersion, PreStateCid: pre, Steps: steps}
+	post2, receipts2, err := Run(store, v2)
+	require.NoError(t, err)
+
+	require.Equal(t, post1, post2)
+	require.Equal(t, receipts1, receipts2)
+}
+
+func TestRunUnknownOpFails(t *testing.T) {
+	store := newTestStore(t)
+	v := &Vector{
+		Version:     CorpusVersion,
+		PreStateCid: newTestPreState(t, store),
+		Steps:       []Step{{Op: "NotARealOp"}},
+	}
+
+	_, receipts, err := Run(store, v)
+	require.NoError(t, err)
+	require.Len(t, receipts, 1)
+	require.False(t, receipts[0].OK)
+	require.NotEmpty(t, receipts[0].Error)
+}
+
+func TestLoadCorpusRejectsUnknownVersion(t *testing.T) {
+	buf, err := json.Marshal([]Vector{{Version: "999"}})
+	require.NoError(t, err)
+
+	_, err = LoadCorpus(bytes.NewReader(buf))
+	require.Error(t, err)
+}
@@ -0,0 +1,112 @@
+// Command gen snapshots a small corpus of conformance vectors from live
+// SCA state transitions, so implementers of alternative subnet actors can
+// validate their own state machine against this package's behavior without
+// needing to run this repo themselves. Run it from the repo root:
+//
+//	go run ./chain/consensus/hierarchical/conformance/gen -out vectors.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/actors/sca"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/conformance"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	cbor "github.com/ipfs/go-ipld-cbor"
+)
+
+func main() {
+	out := flag.String("out", "vectors.json", "path to write the JSON vector corpus to")
+	flag.Parse()
+
+	vectors, err := generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "generating vectors: %s\n", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "creating %s: %s\n", *out, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	if err := conformance.SaveCorpus(f, vectors); err != nil {
+		fmt.Fprintf(os.Stderr, "writing %s: %s\n", *out, err)
+		os.Exit(1)
+	}
+	fmt.Printf("wrote %d vectors to %s\n", len(vectors), *out)
+}
+
+func newStore() adt.Store {
+	bs := blockstore.NewMemorySync()
+	return adt.WrapStore(context.Background(), cbor.NewCborStore(bs))
+}
+
+// generate builds one vector per Step Op this package knows how to replay,
+// each starting from a freshly constructed SCAState.
+func generate() ([]conformance.Vector, error) {
+	ops := []struct {
+		desc   string
+		params interface{}
+		op     string
+	}{
+		{
+			desc:   "CurrWindowCheckpoint template for the first checkpoint period",
+			params: conformance.CurrWindowCheckpointParams{Epoch: 5},
+			op:     "CurrWindowCheckpoint",
+		},
+		{
+			desc:   "RawCheckpoint template at a period boundary",
+			params: conformance.RawCheckpointParams{Epoch: 10},
+			op:     "RawCheckpoint",
+		},
+		{
+			desc:   "storing the same window checkpoint twice is idempotent",
+			params: conformance.PutCheckpointIdempotentParams{Epoch: 3},
+			op:     "PutCheckpointIdempotent",
+		},
+	}
+
+	vectors := make([]conformance.Vector, 0, len(ops))
+	for _, o := range ops {
+		store := newStore()
+		st, err := sca.ConstructSCAState(store, &sca.ConstructorParams{
+			NetworkName:      "/root",
+			CheckpointPeriod: uint64(sca.DefaultCheckpointPeriod),
+		})
+		if err != nil {
+			return nil, err
+		}
+		preCid, err := store.Put(store.Context(), st)
+		if err != nil {
+			return nil, err
+		}
+
+		paramsJSON, err := json.Marshal(o.params)
+		if err != nil {
+			return nil, err
+		}
+		v := conformance.Vector{
+			Version:     conformance.CorpusVersion,
+			Description: o.desc,
+			PreStateCid: preCid.String(),
+			Steps:       []conformance.Step{{Op: o.op, Params: paramsJSON}},
+		}
+
+		postCid, receipts, err := conformance.Run(store, &v)
+		if err != nil {
+			return nil, err
+		}
+		v.PostStateCid = postCid.String()
+		v.Receipts = receipts
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
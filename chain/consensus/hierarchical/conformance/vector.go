@@ -0,0 +1,52 @@
+// Package conformance defines a versioned corpus of test vectors exercising
+// SCA state transitions, so an implementer of an alternative subnet
+// coordinator actor can check their own state machine against a shared,
+// replayable set of cases instead of relying solely on reading this
+// package's own tests.
+//
+// Vectors are scoped to transitions reachable without a mocked
+// runtime.Runtime: this repo has no such harness for the hierarchical
+// actors (subnet_test.go and the subnet/v1 slashing tests all drive
+// state-mutating methods directly against an adt.Store rather than through
+// a simulated VM), so vectors exercising rt-gated actor methods (Register,
+// Fund, CommitChildCheckpoint, ...) are out of scope until one exists.
+package conformance
+
+import "encoding/json"
+
+// CorpusVersion is the current Vector JSON layout version. Bump it whenever
+// Vector's shape changes, so a driver built against an older layout can
+// tell a vector wasn't meant for it instead of misinterpreting its fields.
+const CorpusVersion = "1"
+
+// Vector is one replayable SCA state-transition test case: a pre-state, a
+// sequence of Steps to apply to it, and the post-state/receipts an
+// implementation is expected to reproduce exactly.
+type Vector struct {
+	Version      string
+	Description  string
+	PreStateCid  string
+	Steps        []Step
+	PostStateCid string
+	Receipts     []Receipt
+}
+
+// Step names one state-transition operation and its JSON-encoded params.
+// See Run for the supported Op values.
+type Step struct {
+	Op     string
+	Params json.RawMessage
+}
+
+// Receipt records the outcome Run observed for one Step, for comparison
+// against what a vector claims it should be.
+type Receipt struct {
+	Op    string
+	OK    bool
+	Error string
+}
+
+// Equals reports whether r and o record the same outcome for the same Op.
+func (r Receipt) Equals(o Receipt) bool {
+	return r.Op == o.Op && r.OK == o.OK && r.Error == o.Error
+}
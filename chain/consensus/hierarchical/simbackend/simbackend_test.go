@@ -0,0 +1,98 @@
+package simbackend
+
+import (
+	"testing"
+
+	address "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/consensus/hierarchical/checkpoints/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func mustChildID(t *testing.T, h *SimulatedHierarchy) string {
+	t.Helper()
+	for id := range h.Children {
+		return id
+	}
+	t.Fatal("no children registered")
+	return ""
+}
+
+func TestNewSimulatedHierarchyRegistersChildren(t *testing.T) {
+	h, err := NewSimulatedHierarchy("/root", 2)
+	require.NoError(t, err)
+	require.Len(t, h.Children, 2)
+
+	for id := range h.Children {
+		_, has, err := h.GetSubnet(id)
+		require.NoError(t, err)
+		require.True(t, has)
+	}
+}
+
+func TestInjectTopDownIncrementsNonce(t *testing.T) {
+	h, err := NewSimulatedHierarchy("/root", 1)
+	require.NoError(t, err)
+	id := mustChildID(t, h)
+
+	to, err := address.NewIDAddress(101)
+	require.NoError(t, err)
+
+	require.NoError(t, h.InjectTopDown(id, to, big.NewInt(10)))
+	require.EqualValues(t, 1, h.Parent.Nonce)
+
+	sh, has, err := h.GetSubnet(id)
+	require.NoError(t, err)
+	require.True(t, has)
+	require.EqualValues(t, 1, sh.FundMsgCount)
+}
+
+func TestInjectBottomUpIncrementsNonce(t *testing.T) {
+	h, err := NewSimulatedHierarchy("/root", 1)
+	require.NoError(t, err)
+
+	require.NoError(t, h.InjectBottomUp(schema.CrossMsgMeta{}))
+	require.EqualValues(t, 1, h.Parent.BottomUpNonce)
+}
+
+func TestCommitCheckpointsEveryChild(t *testing.T) {
+	h, err := NewSimulatedHierarchy("/root", 2)
+	require.NoError(t, err)
+
+	require.NoError(t, h.Commit(5))
+	for id := range h.Children {
+		sh, has, err := h.GetSubnet(id)
+		require.NoError(t, err)
+		require.True(t, has)
+		require.NotEqual(t, schema.EmptyCheckpoint, &sh.PrevCheckpoint)
+	}
+}
+
+func TestSimulateMissedCheckpointSkipsOneRound(t *testing.T) {
+	h, err := NewSimulatedHierarchy("/root", 1)
+	require.NoError(t, err)
+	id := mustChildID(t, h)
+
+	h.SimulateMissedCheckpoint(id)
+	require.NoError(t, h.Commit(5))
+
+	sh, has, err := h.GetSubnet(id)
+	require.NoError(t, err)
+	require.True(t, has)
+	require.Equal(t, *schema.EmptyCheckpoint, sh.PrevCheckpoint)
+
+	// The flag only applies to the one round it was set for.
+	require.NoError(t, h.Commit(10))
+	sh, has, err = h.GetSubnet(id)
+	require.NoError(t, err)
+	require.True(t, has)
+	require.NotEqual(t, *schema.EmptyCheckpoint, sh.PrevCheckpoint)
+}
+
+func TestAdvanceEpochsStepsDeterministically(t *testing.T) {
+	h, err := NewSimulatedHierarchy("/root", 1)
+	require.NoError(t, err)
+
+	require.NoError(t, h.AdvanceEpochs(3))
+	require.EqualValues(t, 3, h.Epoch)
+}
@@ -2,10 +2,39 @@ package types
 
 import (
 	"bytes"
+	"io"
 
 	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
 )
 
+// BlockMsgVersion tags the wire framing of an encoded BlockMsg: a single
+// byte written ahead of the version-specific CBOR payload, so a receiver
+// picks the right decoder directly instead of guessing via trial-and-error
+// unmarshal attempts. The previous approach - try the current shape, and
+// on any failure silently retry as the older one - paid a double-unmarshal
+// cost on every message on the hot gossipsub path, and a truly malformed
+// blob could partially decode under the wrong shape instead of erroring
+// clearly.
+type BlockMsgVersion uint64
+
+const (
+	// BlockMsgV1 is the pre-cross-messages wire shape: Header/BlsMessages/
+	// SecpkMessages only.
+	BlockMsgV1 BlockMsgVersion = 1
+	// BlockMsgV2 adds CrossMessages. Current.
+	BlockMsgV2 BlockMsgVersion = 2
+)
+
+// blockMsgDecoders maps a wire version tag to the function that decodes
+// its version-specific payload. Adding a new version (checkpoint
+// attestations, a subnet identifier, ...) means adding an entry here, not
+// another silent-fallback branch in DecodeBlockMsg.
+var blockMsgDecoders = map[BlockMsgVersion]func([]byte) (*BlockMsg, error){
+	BlockMsgV1: decodeBlockMsgV1,
+	BlockMsgV2: decodeBlockMsgV2,
+}
+
 type BlockMsg struct {
 	Header        *BlockHeader
 	BlsMessages   []cid.Cid
@@ -13,37 +42,101 @@ type BlockMsg struct {
 	CrossMessages []cid.Cid
 }
 
+// OldBlockMsg is the CBOR shape of a BlockMsgV1 payload: no CrossMessages.
 type OldBlockMsg struct {
 	Header        *BlockHeader
 	BlsMessages   []cid.Cid
 	SecpkMessages []cid.Cid
 }
 
+// DecodeBlockMsg decodes a wire-encoded BlockMsg. b is expected to start
+// with a one-byte BlockMsgVersion tag (see EncodeBlockMsgV1/V2); for one
+// release cycle it also falls back to treating b as an untagged legacy
+// payload, for peers that haven't upgraded yet.
 func DecodeBlockMsg(b []byte) (*BlockMsg, error) {
-	var bm BlockMsg
-	if err := bm.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
-		// If we couldn't unmarshal the new version of block format,
-		// we try with the old version.
-		var obm OldBlockMsg
-		if err := obm.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
-			return nil, err
+	if len(b) == 0 {
+		return nil, xerrors.Errorf("empty block msg")
+	}
+
+	if decode, ok := blockMsgDecoders[BlockMsgVersion(b[0])]; ok {
+		bm, err := decode(b[1:])
+		if err != nil {
+			return nil, xerrors.Errorf("decoding block msg version %d: %w", b[0], err)
 		}
-		bm.Header = obm.Header
-		bm.BlsMessages = obm.BlsMessages
-		bm.SecpkMessages = obm.SecpkMessages
+		return bm, nil
 	}
 
+	// Legacy shim: b doesn't start with a recognized version tag, so it's
+	// likely untagged bytes from a peer running the pre-versioning wire
+	// format. Try the current shape first, then the older one, before
+	// giving up - same fallback this function always did, now confined to
+	// this one legacy path instead of running on every message.
+	if bm, err := decodeBlockMsgV2(b); err == nil {
+		return bm, nil
+	}
+	bm, err := decodeBlockMsgV1(b)
+	if err != nil {
+		return nil, xerrors.Errorf("decoding legacy untagged block msg: %w", err)
+	}
+	return bm, nil
+}
+
+func decodeBlockMsgV1(b []byte) (*BlockMsg, error) {
+	var obm OldBlockMsg
+	if err := obm.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
+	return &BlockMsg{
+		Header:        obm.Header,
+		BlsMessages:   obm.BlsMessages,
+		SecpkMessages: obm.SecpkMessages,
+	}, nil
+}
+
+func decodeBlockMsgV2(b []byte) (*BlockMsg, error) {
+	var bm BlockMsg
+	if err := bm.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
 	return &bm, nil
 }
 
-func (bm *BlockMsg) Cid() cid.Cid {
-	return bm.Header.Cid()
+// cborMarshaler is the subset of the generated cbor-gen interface
+// encodeBlockMsgVersioned needs from either BlockMsg or OldBlockMsg.
+type cborMarshaler interface {
+	MarshalCBOR(w io.Writer) error
 }
 
-func (bm *BlockMsg) Serialize() ([]byte, error) {
+func encodeBlockMsgVersioned(v BlockMsgVersion, payload cborMarshaler) ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := bm.MarshalCBOR(buf); err != nil {
-		return nil, err
+	buf.WriteByte(byte(v))
+	if err := payload.MarshalCBOR(buf); err != nil {
+		return nil, xerrors.Errorf("marshaling block msg payload: %w", err)
 	}
 	return buf.Bytes(), nil
 }
+
+// EncodeBlockMsgV1 encodes bm in the pre-cross-messages wire shape,
+// discarding CrossMessages, framed with its version tag.
+func EncodeBlockMsgV1(bm *BlockMsg) ([]byte, error) {
+	return encodeBlockMsgVersioned(BlockMsgV1, &OldBlockMsg{
+		Header:        bm.Header,
+		BlsMessages:   bm.BlsMessages,
+		SecpkMessages: bm.SecpkMessages,
+	})
+}
+
+// EncodeBlockMsgV2 encodes bm in the current wire shape, framed with its
+// version tag.
+func EncodeBlockMsgV2(bm *BlockMsg) ([]byte, error) {
+	return encodeBlockMsgVersioned(BlockMsgV2, bm)
+}
+
+func (bm *BlockMsg) Cid() cid.Cid {
+	return bm.Header.Cid()
+}
+
+// Serialize encodes bm in the current (BlockMsgV2) wire shape.
+func (bm *BlockMsg) Serialize() ([]byte, error) {
+	return EncodeBlockMsgV2(bm)
+}
@@ -0,0 +1,96 @@
+package full
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	ipld "github.com/ipfs/go-ipld-format"
+
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+)
+
+// ChainNodeResolver resolves a single `@prefix:arg` path segment for
+// ChainAPI.ChainGetNode. Built-ins cover the HAMT/AMT/actor-state prefixes
+// that used to be hard-coded in resolveOnce; downstream packages (e.g.
+// subnet actors wanting "@subnet:<addr>", or a miner-sector walker wanting
+// "@sectors:<num>") register their own via RegisterChainResolver instead of
+// editing this file.
+type ChainNodeResolver interface {
+	// Prefix is the path-segment prefix this resolver claims, e.g. "@H:".
+	Prefix() string
+	// Resolve consumes names[0] (already confirmed to start with Prefix())
+	// against nd, returning the resulting link and the remaining path
+	// segments still to walk. reg is passed through so a resolver that
+	// rewrites names[0] into another registered prefix (as @Ha:/@Hi:/@Hu:
+	// do into @H:) can recurse through the registry rather than duplicating
+	// another resolver's logic.
+	Resolve(ctx context.Context, reg *ChainNodeResolverRegistry, bs blockstore.Blockstore, tse stmgr.Executor, store adt.Store, ds ipld.NodeGetter, nd ipld.Node, names []string) (*ipld.Link, []string, error)
+}
+
+// ChainNodeResolverRegistry holds the ordered set of resolvers consulted by
+// ChainGetNode. The first registered resolver whose Prefix() matches wins.
+type ChainNodeResolverRegistry struct {
+	mu        sync.RWMutex
+	resolvers []ChainNodeResolver
+}
+
+// NewChainNodeResolverRegistry returns an empty registry; built-ins are
+// added separately by registerBuiltinChainResolvers so tests can construct
+// a registry with only the resolvers they care about.
+func NewChainNodeResolverRegistry() *ChainNodeResolverRegistry {
+	return &ChainNodeResolverRegistry{}
+}
+
+// Register adds r to the registry. Later registrations are only consulted
+// after earlier ones fail to match, so a downstream package should pick a
+// prefix that doesn't collide with "@H:", "@Ha:", "@Hi:", "@Hu:", "@A:" or
+// "@state".
+func (reg *ChainNodeResolverRegistry) Register(r ChainNodeResolver) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.resolvers = append(reg.resolvers, r)
+}
+
+func (reg *ChainNodeResolverRegistry) resolverFor(name string) ChainNodeResolver {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	for _, r := range reg.resolvers {
+		if strings.HasPrefix(name, r.Prefix()) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Resolve looks up a resolver for names[0]'s prefix and invokes it, falling
+// back to the caller on no match (nd.ResolveLink via resolveOnce's default
+// branch) by returning ok=false.
+func (reg *ChainNodeResolverRegistry) Resolve(ctx context.Context, bs blockstore.Blockstore, tse stmgr.Executor, store adt.Store, ds ipld.NodeGetter, nd ipld.Node, names []string) (lnk *ipld.Link, rest []string, ok bool, err error) {
+	r := reg.resolverFor(names[0])
+	if r == nil {
+		return nil, nil, false, nil
+	}
+	lnk, rest, err = r.Resolve(ctx, reg, bs, tse, store, ds, nd, names)
+	return lnk, rest, true, err
+}
+
+// defaultChainNodeResolverRegistry is the registry ChainGetNode consults.
+// RegisterChainResolver adds to it.
+var defaultChainNodeResolverRegistry = func() *ChainNodeResolverRegistry {
+	reg := NewChainNodeResolverRegistry()
+	registerBuiltinChainResolvers(reg)
+	return reg
+}()
+
+// RegisterChainResolver extends ChainAPI.ChainGetNode with a new `@prefix:`
+// path resolver. Call it from an fx invoke in a downstream package's module
+// (e.g. the subnet actor package registering "@subnet:<addr>" to descend
+// into a subnet's cross-msg pool) to make the new prefix resolvable without
+// modifying this package.
+func RegisterChainResolver(r ChainNodeResolver) {
+	defaultChainNodeResolverRegistry.Register(r)
+}
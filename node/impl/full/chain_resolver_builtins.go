@@ -0,0 +1,223 @@
+package full
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	ipld "github.com/ipfs/go-ipld-format"
+	mh "github.com/multiformats/go-multihash"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/vm"
+)
+
+// registerBuiltinChainResolvers installs the six resolvers resolveOnce used
+// to hard-code, in the same order it checked them in.
+func registerBuiltinChainResolvers(reg *ChainNodeResolverRegistry) {
+	reg.Register(hamtAddrResolver{})
+	reg.Register(hamtIntResolver{})
+	reg.Register(hamtUintResolver{})
+	reg.Register(hamtResolver{})
+	reg.Register(amtResolver{})
+	reg.Register(actorStateResolver{})
+}
+
+// hamtResolver resolves "@H:<key>", looking the raw key up in the HAMT
+// rooted at nd.
+type hamtResolver struct{}
+
+func (hamtResolver) Prefix() string { return "@H:" }
+
+func (hamtResolver) Resolve(ctx context.Context, reg *ChainNodeResolverRegistry, bs blockstore.Blockstore, tse stmgr.Executor, store adt.Store, ds ipld.NodeGetter, nd ipld.Node, names []string) (*ipld.Link, []string, error) {
+	h, err := adt.AsMap(store, nd.Cid())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("resolving hamt link: %w", err)
+	}
+
+	var deferred cbg.Deferred
+	if found, err := h.Get(stringKey(names[0][3:]), &deferred); err != nil {
+		return nil, nil, xerrors.Errorf("resolve hamt: %w", err)
+	} else if !found {
+		return nil, nil, xerrors.Errorf("resolve hamt: not found")
+	}
+	var m interface{}
+	if err := cbor.DecodeInto(deferred.Raw, &m); err != nil {
+		return nil, nil, xerrors.Errorf("failed to decode cbor object: %w", err)
+	}
+	if c, ok := m.(cid.Cid); ok {
+		return &ipld.Link{Name: names[0][3:], Cid: c}, names[1:], nil
+	}
+
+	n, err := cbor.WrapObject(m, mh.SHA2_256, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := bs.Put(ctx, n); err != nil {
+		return nil, nil, xerrors.Errorf("put hamt val: %w", err)
+	}
+
+	if len(names) == 1 {
+		return &ipld.Link{Name: names[0][3:], Cid: n.Cid()}, nil, nil
+	}
+
+	return resolveViaRegistry(ctx, reg, bs, tse, ds, n, names[1:])
+}
+
+// hamtAddrResolver resolves "@Ha:<addr>" by rewriting it into the "@H:"
+// form keyed by the address' raw bytes.
+type hamtAddrResolver struct{}
+
+func (hamtAddrResolver) Prefix() string { return "@Ha:" }
+
+func (hamtAddrResolver) Resolve(ctx context.Context, reg *ChainNodeResolverRegistry, bs blockstore.Blockstore, tse stmgr.Executor, store adt.Store, ds ipld.NodeGetter, nd ipld.Node, names []string) (*ipld.Link, []string, error) {
+	addr, err := address.NewFromString(names[0][4:])
+	if err != nil {
+		return nil, nil, xerrors.Errorf("parsing addr: %w", err)
+	}
+	names[0] = "@H:" + string(addr.Bytes())
+	return hamtResolver{}.Resolve(ctx, reg, bs, tse, store, ds, nd, names)
+}
+
+// hamtIntResolver resolves "@Hi:<int64>" by rewriting it into the "@H:"
+// form keyed by abi.IntKey.
+type hamtIntResolver struct{}
+
+func (hamtIntResolver) Prefix() string { return "@Hi:" }
+
+func (hamtIntResolver) Resolve(ctx context.Context, reg *ChainNodeResolverRegistry, bs blockstore.Blockstore, tse stmgr.Executor, store adt.Store, ds ipld.NodeGetter, nd ipld.Node, names []string) (*ipld.Link, []string, error) {
+	i, err := strconv.ParseInt(names[0][4:], 10, 64)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("parsing int64: %w", err)
+	}
+	names[0] = "@H:" + abi.IntKey(i).Key()
+	return hamtResolver{}.Resolve(ctx, reg, bs, tse, store, ds, nd, names)
+}
+
+// hamtUintResolver resolves "@Hu:<uint64>" by rewriting it into the "@H:"
+// form keyed by abi.UIntKey.
+type hamtUintResolver struct{}
+
+func (hamtUintResolver) Prefix() string { return "@Hu:" }
+
+func (hamtUintResolver) Resolve(ctx context.Context, reg *ChainNodeResolverRegistry, bs blockstore.Blockstore, tse stmgr.Executor, store adt.Store, ds ipld.NodeGetter, nd ipld.Node, names []string) (*ipld.Link, []string, error) {
+	i, err := strconv.ParseUint(names[0][4:], 10, 64)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("parsing uint64: %w", err)
+	}
+	names[0] = "@H:" + abi.UIntKey(i).Key()
+	return hamtResolver{}.Resolve(ctx, reg, bs, tse, store, ds, nd, names)
+}
+
+// amtResolver resolves "@A:<index>", looking the index up in the AMT
+// rooted at nd.
+type amtResolver struct{}
+
+func (amtResolver) Prefix() string { return "@A:" }
+
+func (amtResolver) Resolve(ctx context.Context, reg *ChainNodeResolverRegistry, bs blockstore.Blockstore, tse stmgr.Executor, store adt.Store, ds ipld.NodeGetter, nd ipld.Node, names []string) (*ipld.Link, []string, error) {
+	a, err := adt.AsArray(store, nd.Cid())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("load amt: %w", err)
+	}
+
+	idx, err := strconv.ParseUint(names[0][3:], 10, 64)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("parsing amt index: %w", err)
+	}
+
+	var deferred cbg.Deferred
+	if found, err := a.Get(idx, &deferred); err != nil {
+		return nil, nil, xerrors.Errorf("resolve amt: %w", err)
+	} else if !found {
+		return nil, nil, xerrors.Errorf("resolve amt: not found")
+	}
+	var m interface{}
+	if err := cbor.DecodeInto(deferred.Raw, &m); err != nil {
+		return nil, nil, xerrors.Errorf("failed to decode cbor object: %w", err)
+	}
+	if c, ok := m.(cid.Cid); ok {
+		return &ipld.Link{Name: names[0][3:], Cid: c}, names[1:], nil
+	}
+
+	n, err := cbor.WrapObject(m, mh.SHA2_256, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := bs.Put(ctx, n); err != nil {
+		return nil, nil, xerrors.Errorf("put amt val: %w", err)
+	}
+
+	if len(names) == 1 {
+		return &ipld.Link{Name: names[0][3:], Size: 0, Cid: n.Cid()}, nil, nil
+	}
+
+	return resolveViaRegistry(ctx, reg, bs, tse, ds, n, names[1:])
+}
+
+// actorStateResolver resolves "@state", dumping the actor state at nd
+// (interpreted as a types.Actor) into a plain CBOR map via vm.DumpActorState.
+type actorStateResolver struct{}
+
+func (actorStateResolver) Prefix() string { return "@state" }
+
+func (actorStateResolver) Resolve(ctx context.Context, reg *ChainNodeResolverRegistry, bs blockstore.Blockstore, tse stmgr.Executor, store adt.Store, ds ipld.NodeGetter, nd ipld.Node, names []string) (*ipld.Link, []string, error) {
+	var act types.Actor
+	if err := act.UnmarshalCBOR(bytes.NewReader(nd.RawData())); err != nil {
+		return nil, nil, xerrors.Errorf("unmarshaling actor struct for @state: %w", err)
+	}
+
+	head, err := ds.Get(ctx, act.Head)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("getting actor head for @state: %w", err)
+	}
+
+	m, err := vm.DumpActorState(tse.NewActorRegistry(), &act, head.RawData())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// a hack to workaround struct aliasing in refmt
+	ms := map[string]interface{}{}
+	{
+		mstr, err := json.Marshal(m)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal(mstr, &ms); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	n, err := cbor.WrapObject(ms, mh.SHA2_256, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := bs.Put(ctx, n); err != nil {
+		return nil, nil, xerrors.Errorf("put amt val: %w", err)
+	}
+
+	if len(names) == 1 {
+		return &ipld.Link{Name: "state", Size: 0, Cid: n.Cid()}, nil, nil
+	}
+
+	return resolveViaRegistry(ctx, reg, bs, tse, ds, n, names[1:])
+}
+
+// resolveViaRegistry re-enters the registry-driven resolveOnce for the
+// remaining path segments, the same way the old monolithic resolveOnce
+// recursed into itself.
+func resolveViaRegistry(ctx context.Context, reg *ChainNodeResolverRegistry, bs blockstore.Blockstore, tse stmgr.Executor, ds ipld.NodeGetter, nd ipld.Node, names []string) (*ipld.Link, []string, error) {
+	return chainGetNodeResolveOnce(reg, bs, tse)(ctx, ds, nd, names)
+}
@@ -0,0 +1,51 @@
+package full
+
+import (
+	"context"
+	"testing"
+
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+)
+
+// fakeResolver records that it was invoked and returns a fixed link.
+type fakeResolver struct {
+	prefix   string
+	invoked  *bool
+	resolved *ipld.Link
+}
+
+func (f fakeResolver) Prefix() string { return f.prefix }
+
+func (f fakeResolver) Resolve(ctx context.Context, reg *ChainNodeResolverRegistry, bs blockstore.Blockstore, tse stmgr.Executor, store adt.Store, ds ipld.NodeGetter, nd ipld.Node, names []string) (*ipld.Link, []string, error) {
+	*f.invoked = true
+	return f.resolved, names[1:], nil
+}
+
+func TestChainNodeResolverRegistryDispatchesCustomPrefix(t *testing.T) {
+	reg := NewChainNodeResolverRegistry()
+
+	invoked := false
+	want := &ipld.Link{Name: "subnet"}
+	reg.Register(fakeResolver{prefix: "@subnet:", invoked: &invoked, resolved: want})
+
+	lnk, rest, ok, err := reg.Resolve(context.Background(), nil, nil, nil, nil, nil, []string{"@subnet:/root", "tail"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, invoked)
+	require.Same(t, want, lnk)
+	require.Equal(t, []string{"tail"}, rest)
+}
+
+func TestChainNodeResolverRegistryFallsThroughOnNoMatch(t *testing.T) {
+	reg := NewChainNodeResolverRegistry()
+
+	_, _, ok, err := reg.Resolve(context.Background(), nil, nil, nil, nil, nil, []string{"unrecognized"})
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -0,0 +1,188 @@
+package full
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-car"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func testBlock(t *testing.T, data []byte) blocks.Block {
+	h, err := mh.Sum(data, mh.SHA2_256, -1)
+	require.NoError(t, err)
+	c := cid.NewCidV1(cid.Raw, h)
+	b, err := blocks.NewBlockWithCid(data, c)
+	require.NoError(t, err)
+	return b
+}
+
+// TestChainExportRangeWriterDedup ensures that a block routed through the
+// shared dedup set more than once (as happens when two workers discover the
+// same block via different parents) is only ever written to the CAR once.
+func TestChainExportRangeWriterDedup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan []byte, 64)
+	w, errCh := newChainExportRangeWriter(ctx, out, nil)
+
+	blk := testBlock(t, []byte("hello"))
+	seen := cid.NewSet()
+	tryInsert := func(c cid.Cid) bool {
+		if seen.Has(c) {
+			return false
+		}
+		seen.Add(c) //nolint:errcheck
+		return true
+	}
+
+	var written int
+	for i := 0; i < 3; i++ {
+		if tryInsert(blk.Cid()) {
+			require.NoError(t, w.WriteBlock(ctx, blk))
+			written++
+		}
+	}
+	require.Equal(t, 1, written)
+	require.NoError(t, w.Close())
+
+	// errCh yields once the pump has exited. The writer never closes out
+	// itself, so close it here to end the range below.
+	require.NoError(t, <-errCh)
+	close(out)
+
+	var total int
+	for b := range out {
+		total += len(b)
+	}
+	require.Greater(t, total, 0)
+}
+
+// TestChainExportRangeWriterCancel checks that cancelling the context while
+// the pipe pump is blocked on a full output channel unblocks the goroutine
+// instead of deadlocking, mirroring a ctrl-C during a live export.
+func TestChainExportRangeWriterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	out := make(chan []byte) // unbuffered: pump will block until we cancel
+	w, errCh := newChainExportRangeWriter(ctx, out, nil)
+
+	blk := testBlock(t, []byte("blocked"))
+	require.NoError(t, w.WriteBlock(ctx, blk))
+	require.NoError(t, w.Close())
+
+	cancel()
+	err := <-errCh
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+// TestChainExportRangeWriterRoundTripsParentReceiptsAndStateRoot checks that
+// WriteParentReceipts and WriteStateRoot, the way ChainImport's CAR reader
+// would see them, actually put the receipts/state-root bytes on the wire
+// rather than just marking their Cids seen. It's a blockstore-level stand-in
+// for an itest exporting a range from one node and importing it into a
+// fresh one: ChainImport relies on a.Chain.LoadTipSet for a real tipset's
+// roots, which needs a full ChainStore this package can't construct on its
+// own, but the part of the pipeline that regressed (blocks silently missing
+// from the CAR) is exercised here end to end.
+func TestChainExportRangeWriterRoundTripsParentReceiptsAndStateRoot(t *testing.T) {
+	ctx := context.Background()
+
+	src := blockstore.NewMemorySync()
+	receipts := testBlock(t, []byte("parent-receipts"))
+	stateRoot := testBlock(t, []byte("state-root"))
+	require.NoError(t, src.Put(ctx, receipts))
+	require.NoError(t, src.Put(ctx, stateRoot))
+
+	out := make(chan []byte, 64)
+	w, errCh := newChainExportRangeWriter(ctx, out, []cid.Cid{receipts.Cid(), stateRoot.Cid()})
+
+	seen := cid.NewSet()
+	tryInsert := func(c cid.Cid) bool {
+		if seen.Has(c) {
+			return false
+		}
+		seen.Add(c) //nolint:errcheck
+		return true
+	}
+
+	b := &types.BlockHeader{ParentMessageReceipts: receipts.Cid()}
+	require.NoError(t, w.WriteParentReceipts(ctx, src, tryInsert, b))
+	require.NoError(t, w.WriteStateRoot(ctx, src, tryInsert, stateRoot.Cid()))
+	require.NoError(t, w.Close())
+	// The writer never closes out itself; once the pump has exited, close
+	// it so writerPipeForTest's drain loop ends.
+	require.NoError(t, <-errCh)
+	close(out)
+
+	pr, pw := writerPipeForTest(t, out, errCh)
+	defer pr.Close() //nolint:errcheck
+
+	cr, err := car.NewCarReader(pr)
+	require.NoError(t, err)
+
+	dst := blockstore.NewMemorySync()
+	for {
+		blk, err := cr.Next()
+		if err != nil {
+			break
+		}
+		require.NoError(t, dst.Put(ctx, blk))
+	}
+	pw.Close() //nolint:errcheck
+
+	for _, want := range []blocks.Block{receipts, stateRoot} {
+		got, err := dst.Get(ctx, want.Cid())
+		require.NoError(t, err)
+		require.Equal(t, want.RawData(), got.RawData())
+	}
+}
+
+// TestCarReaderFromFeedClosesPipeOnMalformedHeader checks that a malformed
+// CAR header doesn't leak the feeder goroutine carReaderFromFeed starts:
+// car.NewCarReader can fail partway through reading the header, leaving
+// its last pw.Write call permanently blocked with nothing left to read
+// pr unless carReaderFromFeed's error path closes it.
+func TestCarReaderFromFeedClosesPipeOnMalformedHeader(t *testing.T) {
+	ctx := context.Background()
+	before := runtime.NumGoroutine()
+
+	r := make(chan []byte, 1)
+	r <- []byte("this is not a valid CAR header")
+	close(r)
+
+	_, err := carReaderFromFeed(ctx, r)
+	require.Error(t, err)
+
+	require.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before
+	}, time.Second, 10*time.Millisecond, "feeder goroutine leaked after a malformed CAR header")
+}
+
+// writerPipeForTest drains out into a fresh io.Pipe reader the way
+// carReaderFromFeed drains ChainImport's byte feed, so car.NewCarReader can
+// read the exported bytes directly. The drain ends only once out is closed.
+func writerPipeForTest(t *testing.T, out <-chan []byte, errCh <-chan error) (*io.PipeReader, *io.PipeWriter) {
+	t.Helper()
+	pr, pw := io.Pipe()
+	go func() {
+		for b := range out {
+			if _, err := pw.Write(b); err != nil {
+				return
+			}
+		}
+		require.NoError(t, <-errCh)
+		pw.Close() //nolint:errcheck
+	}()
+	return pr, pw
+}